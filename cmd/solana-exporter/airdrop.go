@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/naviat/solana-rpc-exporter/pkg/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	// AirdropSuccess means the airdrop transaction reached confirmed commitment
+	AirdropSuccess = "success"
+	// AirdropRateLimited means the faucet refused the airdrop for exceeding its rate limit
+	AirdropRateLimited = "rate_limited"
+	// AirdropFailed means the airdrop transaction landed with an error
+	AirdropFailed = "failed"
+	// AirdropTimeout means the airdrop transaction was not confirmed within airdropConfirmTimeout
+	AirdropTimeout = "timeout"
+	// AirdropError means the airdrop request failed for another reason
+	AirdropError = "error"
+
+	// airdropPollInterval is how often the status of the airdrop transaction is checked
+	airdropPollInterval = 500 * time.Millisecond
+	// airdropConfirmTimeout is how long an airdrop transaction may take to be confirmed
+	airdropConfirmTimeout = time.Minute
+)
+
+type (
+	// AirdropProbe configures the faucet probe; it is disabled without an Address
+	AirdropProbe struct {
+		Address  string
+		Lamports int64
+		Interval time.Duration
+	}
+
+	// AirdropProber requests a small airdrop from the node's faucet at an interval and follows the
+	// transaction to confirmation, measuring the faucet developers on devnet and testnet rely on.
+	// There is no faucet on mainnet-beta, where the configuration refuses the probe.
+	AirdropProber struct {
+		client *rpc.Client
+		logger *zap.SugaredLogger
+		config *ExporterConfig
+		// pollInterval and confirmTimeout pace the wait for confirmation
+		pollInterval   time.Duration
+		confirmTimeout time.Duration
+
+		Probes           *prometheus.CounterVec
+		ConfirmationTime *prometheus.HistogramVec
+		LastSuccess      *prometheus.GaugeVec
+	}
+)
+
+func NewAirdropProber(client *rpc.Client, config *ExporterConfig) *AirdropProber {
+	return &AirdropProber{
+		client:         client,
+		logger:         slog.Get(),
+		config:         config,
+		pollInterval:   airdropPollInterval,
+		confirmTimeout: airdropConfirmTimeout,
+
+		Probes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "solana_airdrop_probes_total",
+				Help: "Airdrop probes by outcome (success, rate_limited, failed, timeout or error)",
+			},
+			[]string{NetworkLabel, StatusLabel},
+		),
+		ConfirmationTime: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "solana_airdrop_confirmation_seconds",
+				Help:    "Time from requesting an airdrop until its transaction was seen at confirmed commitment",
+				Buckets: []float64{.5, 1, 2, 3, 5, 8, 13, 20, 30, 45, 60},
+			},
+			[]string{NetworkLabel},
+		),
+		LastSuccess: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "solana_airdrop_last_success_timestamp_seconds",
+				Help: "Unix time of the last airdrop probe that was confirmed",
+			},
+			[]string{NetworkLabel},
+		),
+	}
+}
+
+func (p *AirdropProber) WatchAirdrops(ctx context.Context) error {
+	ticker := time.NewTicker(p.config.AirdropProbe.Interval)
+	defer ticker.Stop()
+
+	for {
+		status, elapsed := p.probe(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		p.record(status, elapsed)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe requests an airdrop and waits for its transaction to be confirmed, returning the outcome
+// and, on success, how long confirmation took
+func (p *AirdropProber) probe(ctx context.Context) (string, time.Duration) {
+	target := p.config.AirdropProbe
+	start := time.Now()
+	signature, err := p.client.RequestAirdrop(ctx, rpc.CommitmentConfirmed, target.Address, target.Lamports)
+	if err != nil {
+		if rpc.IsRateLimited(err) {
+			p.logger.Warnw("Airdrop was rate limited", "address", target.Address, "error", err)
+			return AirdropRateLimited, 0
+		}
+		p.logger.Errorw("Failed to request airdrop", "address", target.Address, "error", err)
+		return AirdropError, 0
+	}
+
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+	deadline := start.Add(p.confirmTimeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return AirdropError, 0
+		case <-ticker.C:
+		}
+
+		statuses, err := p.client.GetSignatureStatuses(ctx, []string{signature})
+		if err != nil {
+			p.logger.Debugw("Failed to get airdrop status", "signature", signature, "error", err)
+		} else if len(statuses) > 0 && statuses[0] != nil {
+			status := statuses[0]
+			if status.Err != nil {
+				p.logger.Errorw("Airdrop transaction failed", "signature", signature, "error", status.Err)
+				return AirdropFailed, 0
+			}
+			if status.ConfirmationStatus == string(rpc.CommitmentConfirmed) ||
+				status.ConfirmationStatus == string(rpc.CommitmentFinalized) {
+				return AirdropSuccess, time.Since(start)
+			}
+		}
+
+		if time.Now().After(deadline) {
+			p.logger.Warnw("Airdrop transaction was not confirmed in time", "signature", signature)
+			return AirdropTimeout, 0
+		}
+	}
+}
+
+func (p *AirdropProber) record(status string, elapsed time.Duration) {
+	p.Probes.WithLabelValues(p.config.NetworkName, status).Inc()
+	if status == AirdropSuccess {
+		p.ConfirmationTime.WithLabelValues(p.config.NetworkName).Observe(elapsed.Seconds())
+		p.LastSuccess.WithLabelValues(p.config.NetworkName).SetToCurrentTime()
+	}
+}
+
+func (p *AirdropProber) Describe(ch chan<- *prometheus.Desc) {
+	p.Probes.Describe(ch)
+	p.ConfirmationTime.Describe(ch)
+	p.LastSuccess.Describe(ch)
+}
+
+func (p *AirdropProber) Collect(ch chan<- prometheus.Metric) {
+	p.Probes.Collect(ch)
+	p.ConfirmationTime.Collect(ch)
+	p.LastSuccess.Collect(ch)
+}
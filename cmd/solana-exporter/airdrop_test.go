@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAirdropProber(t *testing.T) {
+	server, client := rpc.NewMockClient(t, map[string]any{})
+	server.SetOpt(rpc.AirdropOpt, "addressA", "confirmed")
+	server.SetOpt(rpc.AirdropOpt, "addressB", "processed")
+	server.SetOpt(rpc.AirdropOpt, "addressC", &rpc.RPCError{Code: rpc.RateLimitedCode, Message: "Too many requests"})
+	server.SetOpt(rpc.AirdropOpt, "addressE", map[string]any{
+		"confirmationStatus": "confirmed", "err": map[string]any{"InstructionError": []any{0, "Custom"}},
+	})
+	config := &ExporterConfig{NetworkName: "devnet", AirdropProbe: AirdropProbe{Lamports: 1_000_000}}
+	prober := NewAirdropProber(client, config)
+	prober.pollInterval = 10 * time.Millisecond
+	prober.confirmTimeout = 50 * time.Millisecond
+	ctx := context.Background()
+
+	tests := []struct {
+		address string
+		status  string
+	}{
+		{"addressA", AirdropSuccess},
+		// never gets past processed
+		{"addressB", AirdropTimeout},
+		{"addressC", AirdropRateLimited},
+		// unconfigured addresses get the faucet's generic failure, which is not known to be the rate limit
+		{"addressD", AirdropError},
+		// landed, but the transaction failed
+		{"addressE", AirdropFailed},
+	}
+	for _, test := range tests {
+		t.Run(test.address, func(t *testing.T) {
+			config.AirdropProbe.Address = test.address
+			status, elapsed := prober.probe(ctx)
+			assert.Equal(t, test.status, status)
+			if status == AirdropSuccess {
+				assert.Greater(t, elapsed, time.Duration(0))
+			}
+			prober.record(status, elapsed)
+		})
+	}
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(prober.Probes.WithLabelValues("devnet", AirdropSuccess)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(prober.Probes.WithLabelValues("devnet", AirdropRateLimited)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(prober.Probes.WithLabelValues("devnet", AirdropError)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(prober.Probes.WithLabelValues("devnet", AirdropFailed)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(prober.Probes.WithLabelValues("devnet", AirdropTimeout)))
+	assert.Equal(t, 1, testutil.CollectAndCount(prober, "solana_airdrop_confirmation_seconds"))
+	assert.Equal(t, 1, testutil.CollectAndCount(prober, "solana_airdrop_last_success_timestamp_seconds"))
+}
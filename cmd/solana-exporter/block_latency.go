@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/naviat/solana-rpc-exporter/pkg/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	ReferenceLabel = "reference"
+	// ReferenceBlockTime measures from the block's blockTime, the cluster's stake-weighted clock
+	ReferenceBlockTime = "block_time"
+	// ReferenceProcessed measures from when the slot was first seen as the node's processed slot
+	ReferenceProcessed = "processed"
+	// ReferenceSlotStart measures from the slot's estimated start time, see slotClock
+	ReferenceSlotStart = "slot_start"
+
+	// BlockPollTick is the resolution of block fetch attempts
+	BlockPollTick = 50 * time.Millisecond
+	// SlotPollInterval is how often the node's processed slot is checked for new slots
+	SlotPollInterval = 200 * time.Millisecond
+	// minBlockRetry and maxBlockRetry bound the backoff between fetch attempts of a block
+	minBlockRetry = 100 * time.Millisecond
+	maxBlockRetry = 2 * time.Second
+	// BlockFetchTimeout is how long a block is retried before it is counted as never arriving
+	BlockFetchTimeout = time.Minute
+	// maxPendingBlocks bounds catch-up when the node jumps ahead, e.g. after a restart
+	maxPendingBlocks = 64
+	// slotClockWindow is how many slots apart the blockTime anchors of the slot clock are taken,
+	// long enough for blockTime's one-second resolution to barely affect the measured slot time
+	slotClockWindow = 1500
+	// targetSlotTime is the cluster's target slot duration
+	targetSlotTime = 400 * time.Millisecond
+)
+
+type (
+	pendingBlock struct {
+		slot        int64
+		seen        time.Time
+		attempts    int
+		nextAttempt time.Time
+	}
+
+	blockFetch struct {
+		block    *rpc.Block
+		err      error
+		received time.Time
+	}
+
+	// slotClock estimates when slots started by extrapolating from a blockTime anchor with
+	// the slot time measured between the last two anchors (the 400ms target until then)
+	slotClock struct {
+		anchorSlot int64
+		anchorTime time.Time
+		slotTime   time.Duration
+	}
+
+	// BlockLatencyWatcher measures how long after a slot a confirmed block becomes fetchable
+	// from the node: new slots are picked up from the processed slot and getBlock is retried
+	// on a short backoff until it succeeds. This includes turbine and replay delay as seen by
+	// an RPC consumer, which slot height snapshots cannot show.
+	BlockLatencyWatcher struct {
+		client   *rpc.Client
+		logger   *zap.SugaredLogger
+		config   *ExporterConfig
+		endpoint string
+
+		// only touched by the watching goroutine
+		lastSlot     int64
+		lastSlotPoll time.Time
+		pending      []*pendingBlock
+		clock        slotClock
+
+		ArrivalLatency  *prometheus.HistogramVec
+		ArrivalTimeouts *prometheus.CounterVec
+	}
+)
+
+func NewBlockLatencyWatcher(client *rpc.Client, config *ExporterConfig) *BlockLatencyWatcher {
+	return &BlockLatencyWatcher{
+		client:   client,
+		logger:   slog.Get(),
+		config:   config,
+		endpoint: rpc.EndpointLabel(config.RpcUrl),
+
+		ArrivalLatency: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name: "solana_block_arrival_latency_seconds",
+				Help: "Time from the reference point until the confirmed block was first fetchable from the endpoint",
+				// sub-second buckets for processed, which is measured locally; block_time and
+				// slot_start cannot resolve finer than blockTime's whole seconds
+				Buckets: []float64{.1, .2, .4, .8, 1, 2, 3, 4, 5, 6, 8, 10, 13, 20, 30, 60},
+			},
+			[]string{NetworkLabel, EndpointLabel, ReferenceLabel},
+		),
+		ArrivalTimeouts: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "solana_block_arrival_timeouts_total",
+				Help: "Slots whose confirmed block did not become fetchable within the fetch timeout (skipped slots excluded)",
+			},
+			[]string{NetworkLabel, EndpointLabel},
+		),
+	}
+}
+
+func (w *BlockLatencyWatcher) WatchBlocks(ctx context.Context) error {
+	ticker := time.NewTicker(BlockPollTick)
+	defer ticker.Stop()
+
+	for {
+		w.poll(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll picks up new slots when due and attempts the blocks whose retry is due
+func (w *BlockLatencyWatcher) poll(ctx context.Context, now time.Time) {
+	if now.Sub(w.lastSlotPoll) >= SlotPollInterval {
+		w.lastSlotPoll = now
+		slot, err := w.client.GetSlot(ctx, rpc.CommitmentProcessed)
+		if err != nil {
+			w.logger.Errorw("Failed to get processed slot", "error", err)
+		} else {
+			w.addSlots(slot, now)
+		}
+	}
+
+	var due []*pendingBlock
+	for _, block := range w.pending {
+		if !now.Before(block.nextAttempt) {
+			due = append(due, block)
+		}
+	}
+	if len(due) == 0 {
+		return
+	}
+
+	// attempts run concurrently so a slow response does not delay the other slots
+	results := make([]blockFetch, len(due))
+	var wg sync.WaitGroup
+	for i, block := range due {
+		wg.Add(1)
+		go func(i int, slot int64) {
+			defer wg.Done()
+			fetched, err := w.client.GetBlock(ctx, rpc.CommitmentConfirmed, slot, "none")
+			results[i] = blockFetch{block: fetched, err: err, received: time.Now()}
+		}(i, block.slot)
+	}
+	wg.Wait()
+
+	done := make(map[int64]bool)
+	for i, block := range due {
+		if w.handleFetch(block, results[i], now) {
+			done[block.slot] = true
+		}
+	}
+	remaining := w.pending[:0]
+	for _, block := range w.pending {
+		if !done[block.slot] {
+			remaining = append(remaining, block)
+		}
+	}
+	w.pending = remaining
+}
+
+// addSlots queues the slots between the last seen processed slot and slot
+func (w *BlockLatencyWatcher) addSlots(slot int64, now time.Time) {
+	if slot <= w.lastSlot {
+		return
+	}
+	first := w.lastSlot + 1
+	if w.lastSlot == 0 || slot-first >= maxPendingBlocks {
+		// only the newest slot has a meaningful first-seen time
+		first = slot
+	}
+	for s := first; s <= slot; s++ {
+		w.pending = append(w.pending, &pendingBlock{slot: s, seen: now, nextAttempt: now})
+	}
+	w.lastSlot = slot
+
+	if len(w.pending) > maxPendingBlocks {
+		w.pending = w.pending[len(w.pending)-maxPendingBlocks:]
+	}
+}
+
+// handleFetch records a fetch attempt and returns whether the slot is finished with
+func (w *BlockLatencyWatcher) handleFetch(block *pendingBlock, fetch blockFetch, now time.Time) bool {
+	if fetch.err == nil {
+		w.observe(block.slot, ReferenceProcessed, fetch.received.Sub(block.seen))
+		if fetch.block.BlockTime > 0 {
+			blockTime := time.Unix(fetch.block.BlockTime, 0)
+			w.observe(block.slot, ReferenceBlockTime, fetch.received.Sub(blockTime))
+			w.clock.update(block.slot, blockTime)
+		}
+		if start, ok := w.clock.estimate(block.slot); ok {
+			w.observe(block.slot, ReferenceSlotStart, fetch.received.Sub(start))
+		}
+		return true
+	}
+	if rpc.IsSlotSkipped(fetch.err) {
+		return true
+	}
+	if now.Sub(block.seen) >= BlockFetchTimeout {
+		w.ArrivalTimeouts.WithLabelValues(w.config.NetworkName, w.endpoint).Inc()
+		w.logger.Warnw("Confirmed block never became available", "slot", block.slot, "error", fetch.err)
+		return true
+	}
+	if !rpc.IsBlockNotAvailable(fetch.err) {
+		w.logger.Debugw("Failed to fetch block", "slot", block.slot, "error", fetch.err)
+	}
+
+	block.attempts++
+	retry := minBlockRetry << min(block.attempts-1, 8)
+	block.nextAttempt = now.Add(min(retry, maxBlockRetry))
+	return false
+}
+
+// update takes slot as the new anchor once it is slotClockWindow slots past the current one
+func (c *slotClock) update(slot int64, blockTime time.Time) {
+	switch {
+	case c.anchorSlot == 0:
+		c.slotTime = targetSlotTime
+	case slot-c.anchorSlot < slotClockWindow:
+		return
+	default:
+		c.slotTime = blockTime.Sub(c.anchorTime) / time.Duration(slot-c.anchorSlot)
+	}
+	c.anchorSlot = slot
+	c.anchorTime = blockTime
+}
+
+// estimate returns the estimated start time of slot, if an anchor has been taken
+func (c *slotClock) estimate(slot int64) (time.Time, bool) {
+	if c.anchorSlot == 0 {
+		return time.Time{}, false
+	}
+	return c.anchorTime.Add(time.Duration(slot-c.anchorSlot) * c.slotTime), true
+}
+
+// observe records a latency with the slot as exemplar, so outliers can be traced to a block
+func (w *BlockLatencyWatcher) observe(slot int64, reference string, latency time.Duration) {
+	observer := w.ArrivalLatency.WithLabelValues(w.config.NetworkName, w.endpoint, reference)
+	if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok {
+		exemplarObserver.ObserveWithExemplar(latency.Seconds(), prometheus.Labels{"slot": strconv.FormatInt(slot, 10)})
+		return
+	}
+	observer.Observe(latency.Seconds())
+}
+
+func (w *BlockLatencyWatcher) Describe(ch chan<- *prometheus.Desc) {
+	w.ArrivalLatency.Describe(ch)
+	w.ArrivalTimeouts.Describe(ch)
+}
+
+func (w *BlockLatencyWatcher) Collect(ch chan<- prometheus.Metric) {
+	w.ArrivalLatency.Collect(ch)
+	w.ArrivalTimeouts.Collect(ch)
+}
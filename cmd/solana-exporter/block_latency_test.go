@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockLatencyWatcher(t *testing.T) {
+	server, client := rpc.NewMockClient(t, map[string]any{"getSlot": 100})
+	blockTime := time.Now().Add(-2 * time.Second).Unix()
+	server.SetOpt(rpc.BlockOpt, int64(100), map[string]any{"blockTime": blockTime, "blockhash": "hash100"})
+	server.SetOpt(rpc.BlockOpt, int64(101), &rpc.RPCError{Code: rpc.SlotSkippedCode, Message: "Slot 101 was skipped"})
+	server.SetOpt(rpc.BlockOpt, int64(102), map[string]any{"blockTime": blockTime, "blockhash": "hash102"})
+
+	watcher := NewBlockLatencyWatcher(client, &ExporterConfig{NetworkName: "mainnet-beta", RpcUrl: server.URL()})
+	ctx := context.Background()
+	start := time.Now()
+
+	// the first slot seen is fetched straight away
+	watcher.poll(ctx, start)
+	assert.Equal(t, int64(100), watcher.lastSlot)
+	assert.Empty(t, watcher.pending)
+
+	// skipped slots are dropped, unavailable blocks are retried with backoff
+	server.SetOpt(rpc.EasyResultsOpt, "getSlot", 103)
+	watcher.poll(ctx, start.Add(SlotPollInterval))
+	if assert.Len(t, watcher.pending, 1) {
+		assert.Equal(t, int64(103), watcher.pending[0].slot)
+		assert.Equal(t, 1, watcher.pending[0].attempts)
+		assert.Equal(t, start.Add(SlotPollInterval+minBlockRetry), watcher.pending[0].nextAttempt)
+	}
+
+	// not due yet
+	watcher.poll(ctx, start.Add(SlotPollInterval+minBlockRetry/2))
+	assert.Equal(t, 1, watcher.pending[0].attempts)
+
+	// a block that never shows up is eventually given up on
+	watcher.poll(ctx, start.Add(SlotPollInterval+BlockFetchTimeout))
+	assert.Empty(t, watcher.pending)
+	assert.Equal(t, 1.0, testutil.ToFloat64(watcher.ArrivalTimeouts.WithLabelValues("mainnet-beta", rpc.EndpointLabel(server.URL()))))
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(watcher)
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+
+	samples := make(map[string]uint64)
+	for _, family := range families {
+		if family.GetName() != "solana_block_arrival_latency_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == ReferenceLabel {
+					samples[label.GetValue()] = metric.GetHistogram().GetSampleCount()
+				}
+			}
+			hasExemplar := false
+			for _, bucket := range metric.GetHistogram().GetBucket() {
+				if bucket.GetExemplar() != nil {
+					hasExemplar = true
+					assert.Equal(t, "slot", bucket.GetExemplar().GetLabel()[0].GetName())
+				}
+			}
+			assert.True(t, hasExemplar)
+		}
+	}
+	assert.Equal(t, map[string]uint64{ReferenceProcessed: 2, ReferenceBlockTime: 2, ReferenceSlotStart: 2}, samples)
+}
+
+func TestSlotClock(t *testing.T) {
+	var clock slotClock
+	_, ok := clock.estimate(1000)
+	assert.False(t, ok)
+
+	anchor := time.Unix(1_700_000_000, 0)
+	clock.update(1000, anchor)
+	start, ok := clock.estimate(1010)
+	assert.True(t, ok)
+	assert.Equal(t, anchor.Add(10*targetSlotTime), start)
+
+	// blocks within the window do not move the anchor
+	clock.update(1100, anchor.Add(time.Minute))
+	assert.Equal(t, int64(1000), clock.anchorSlot)
+
+	// the slot time is measured between anchors a window apart
+	clock.update(1000+slotClockWindow, anchor.Add(slotClockWindow*450*time.Millisecond))
+	assert.Equal(t, 450*time.Millisecond, clock.slotTime)
+	start, _ = clock.estimate(1000 + slotClockWindow + 2)
+	assert.Equal(t, anchor.Add((slotClockWindow+2)*450*time.Millisecond), start)
+}
+
+func TestBlockLatencyWatcher_AddSlots(t *testing.T) {
+	watcher := NewBlockLatencyWatcher(nil, &ExporterConfig{NetworkName: "mainnet-beta"})
+	now := time.Now()
+
+	watcher.addSlots(1000, now)
+	watcher.addSlots(1003, now)
+	assert.Len(t, watcher.pending, 4)
+
+	// the slot going backwards (e.g. a fork switch) adds nothing
+	watcher.addSlots(1002, now)
+	assert.Len(t, watcher.pending, 4)
+
+	// a large jump only queues the newest slot
+	watcher.addSlots(5000, now)
+	assert.Len(t, watcher.pending, 5)
+	assert.Equal(t, int64(5000), watcher.pending[4].slot)
+}
@@ -123,6 +123,8 @@ func (c *SolanaCollector) Describe(ch chan<- *prometheus.Desc) {
 
 func (c *SolanaCollector) Collect(ch chan<- prometheus.Metric) {
 	ctx := context.Background()
+	// health and slot height are what alerting relies on, keep them ahead of everything else
+	criticalCtx := rpc.WithPriority(ctx, rpc.PriorityCritical)
 	totalStart := time.Now()
 	var version string
 	var numSlotsBehind int64
@@ -153,7 +155,7 @@ func (c *SolanaCollector) Collect(ch chan<- prometheus.Metric) {
 	}
 
 	// Health check and slots behind
-	_, err := c.rpcClient.GetHealth(ctx)
+	_, err := c.rpcClient.GetHealth(criticalCtx)
 	isHealthy := 0 // Default to unhealthy
 	if err != nil {
 		var rpcError *rpc.RPCError
@@ -188,7 +190,7 @@ func (c *SolanaCollector) Collect(ch chan<- prometheus.Metric) {
 	}
 
 	// Collect epoch info and block/slot heights
-	epochInfo, err := c.rpcClient.GetEpochInfo(ctx, rpc.CommitmentConfirmed)
+	epochInfo, err := c.rpcClient.GetEpochInfo(criticalCtx, rpc.CommitmentConfirmed)
 	if err == nil {
 		ch <- c.NodeEpoch.MustNewConstMetric(float64(epochInfo.Epoch), c.config.NetworkName)
 		ch <- c.NodeTransactionCount.MustNewConstMetric(float64(epochInfo.TransactionCount), c.config.NetworkName)
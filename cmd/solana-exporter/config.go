@@ -3,17 +3,42 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"github.com/naviat/solana-rpc-exporter/pkg/slog"
 	"time"
 )
 
 type ExporterConfig struct {
-	HttpTimeout   time.Duration
-	RpcUrl        string
-	ListenAddress string
-	SlotPace      time.Duration
-	NetworkName   string
-	Debug         bool
+	HttpTimeout            time.Duration
+	RpcUrl                 string
+	ListenAddress          string
+	SlotPace               time.Duration
+	NetworkName            string
+	Debug                  bool
+	RpcMaxConcurrency      int
+	GeoIPDatabases         []string
+	Identities             []string
+	LedgerPath             string
+	AccountsPath           string
+	SnapshotsPath          string
+	SafeToRestart          bool
+	RestartPeers           []string
+	RestartRules           RestartRules
+	SquadsMultisigs        []string
+	BlockLatency           bool
+	RollbackDetection      bool
+	RecordingRules         string
+	ApyStakeAccounts       []string
+	ApyEpochs              int
+	PriorityFeeAPI         bool
+	PriorityFeeLimits      PriorityFeeLimits
+	VersionPolicy          string
+	AuditEndpoints         []string
+	AirdropProbe           AirdropProbe
+	DelegationVoteAccounts []string
+	DelegationEventSol     int64
+	TokenMints             []string
+	NativeHealth           bool
 }
 
 func NewExporterConfig(
@@ -24,6 +49,30 @@ func NewExporterConfig(
 	slotPace time.Duration,
 	networkName string,
 	debug bool,
+	rpcMaxConcurrency int,
+	geoIPDatabases []string,
+	identities []string,
+	ledgerPath string,
+	accountsPath string,
+	snapshotsPath string,
+	safeToRestart bool,
+	restartPeers []string,
+	restartRules RestartRules,
+	squadsMultisigs []string,
+	blockLatency bool,
+	rollbackDetection bool,
+	recordingRules string,
+	apyStakeAccounts []string,
+	apyEpochs int,
+	priorityFeeAPI bool,
+	priorityFeeLimits PriorityFeeLimits,
+	versionPolicy string,
+	auditEndpoints []string,
+	airdropProbe AirdropProbe,
+	delegationVoteAccounts []string,
+	delegationEventSol int64,
+	tokenMints []string,
+	nativeHealth bool,
 ) (*ExporterConfig, error) {
 	logger := slog.Get()
 	logger.Infow(
@@ -34,27 +83,124 @@ func NewExporterConfig(
 		"networkName", networkName,
 		"slotPace", slotPace.Seconds(),
 		"debug", debug,
+		"rpcMaxConcurrency", rpcMaxConcurrency,
+		"geoIPDatabases", geoIPDatabases,
+		"identities", identities,
+		"ledgerPath", ledgerPath,
+		"accountsPath", accountsPath,
+		"snapshotsPath", snapshotsPath,
+		"safeToRestart", safeToRestart,
+		"restartPeers", len(restartPeers),
+		"restartRules", restartRules,
+		"squadsMultisigs", squadsMultisigs,
+		"blockLatency", blockLatency,
+		"rollbackDetection", rollbackDetection,
+		"recordingRules", recordingRules,
+		"apyStakeAccounts", apyStakeAccounts,
+		"apyEpochs", apyEpochs,
+		"priorityFeeAPI", priorityFeeAPI,
+		"priorityFeeLimits", priorityFeeLimits,
+		"versionPolicy", versionPolicy,
+		"auditEndpoints", auditEndpoints,
+		"airdropProbe", airdropProbe,
+		"delegationVoteAccounts", delegationVoteAccounts,
+		"delegationEventSol", delegationEventSol,
+		"tokenMints", tokenMints,
+		"nativeHealth", nativeHealth,
 	)
+	if restartRules.MinHealthyPeers < 0 || restartRules.MinHealthyPeers > len(restartPeers) {
+		return nil, fmt.Errorf(
+			"restart min healthy peers must be between 0 and the %d restart peers, got %d",
+			len(restartPeers), restartRules.MinHealthyPeers,
+		)
+	}
+	if apyEpochs < 1 {
+		return nil, fmt.Errorf("apy epochs must be at least 1, got %d", apyEpochs)
+	}
+	if priorityFeeLimits.Ceiling > 0 && priorityFeeLimits.Ceiling < priorityFeeLimits.Floor {
+		return nil, fmt.Errorf(
+			"priority fee ceiling %d is below the floor %d", priorityFeeLimits.Ceiling, priorityFeeLimits.Floor,
+		)
+	}
+	if airdropProbe.Address != "" {
+		// there is no faucet on mainnet-beta, and asking for one there only spams the node
+		if networkName == "mainnet-beta" {
+			return nil, fmt.Errorf("the airdrop probe is refused on mainnet-beta")
+		}
+		if airdropProbe.Lamports < 1 || airdropProbe.Interval <= 0 {
+			return nil, fmt.Errorf("the airdrop probe needs positive lamports and interval")
+		}
+	}
 
 	config := ExporterConfig{
-		HttpTimeout:   httpTimeout,
-		RpcUrl:        rpcUrl,
-		ListenAddress: listenAddress,
-		SlotPace:      slotPace,
-		NetworkName:   networkName,
-		Debug:         debug,
+		HttpTimeout:            httpTimeout,
+		RpcUrl:                 rpcUrl,
+		ListenAddress:          listenAddress,
+		SlotPace:               slotPace,
+		NetworkName:            networkName,
+		Debug:                  debug,
+		RpcMaxConcurrency:      rpcMaxConcurrency,
+		GeoIPDatabases:         geoIPDatabases,
+		Identities:             identities,
+		LedgerPath:             ledgerPath,
+		AccountsPath:           accountsPath,
+		SnapshotsPath:          snapshotsPath,
+		SafeToRestart:          safeToRestart,
+		RestartPeers:           restartPeers,
+		RestartRules:           restartRules,
+		SquadsMultisigs:        squadsMultisigs,
+		BlockLatency:           blockLatency,
+		RollbackDetection:      rollbackDetection,
+		RecordingRules:         recordingRules,
+		ApyStakeAccounts:       apyStakeAccounts,
+		ApyEpochs:              apyEpochs,
+		PriorityFeeAPI:         priorityFeeAPI,
+		PriorityFeeLimits:      priorityFeeLimits,
+		VersionPolicy:          versionPolicy,
+		AuditEndpoints:         auditEndpoints,
+		AirdropProbe:           airdropProbe,
+		DelegationVoteAccounts: delegationVoteAccounts,
+		DelegationEventSol:     delegationEventSol,
+		TokenMints:             tokenMints,
+		NativeHealth:           nativeHealth,
 	}
 	return &config, nil
 }
 
 func NewExporterConfigFromCLI(ctx context.Context) (*ExporterConfig, error) {
 	var (
-		httpTimeout   int
-		rpcUrl        string
-		listenAddress string
-		slotPace      int
-		networkName   string
-		debug         bool
+		httpTimeout       int
+		rpcUrl            string
+		listenAddress     string
+		slotPace          int
+		networkName       string
+		debug             bool
+		rpcMaxConcurrency int
+		geoIPDatabases    string
+		identities        string
+		ledgerPath        string
+		accountsPath      string
+		snapshotsPath     string
+		discoverLocal     bool
+		safeToRestart     bool
+		restartPeers      string
+		restartRules      RestartRules
+		squadsMultisigs   string
+		blockLatency      bool
+		rollbackDetection bool
+		recordingRules    string
+		apyStakeAccounts  string
+		apyEpochs         int
+		priorityFeeAPI    bool
+		priorityFeeLimits PriorityFeeLimits
+		versionPolicy     string
+		auditEndpoints    string
+		airdropProbe      AirdropProbe
+		airdropInterval   int
+		delegationVotes   string
+		delegationEvent   int64
+		tokenMints        string
+		nativeHealth      bool
 	)
 
 	flag.IntVar(
@@ -94,8 +240,218 @@ func NewExporterConfigFromCLI(ctx context.Context) (*ExporterConfig, error) {
 		false,
 		"Enable debug logging",
 	)
+	flag.IntVar(
+		&rpcMaxConcurrency,
+		"rpc-max-concurrency",
+		8,
+		"Maximum number of concurrent normal and bulk priority RPC calls "+
+			"(critical health and slot checks are only bounded by their own class limit)",
+	)
+	flag.StringVar(
+		&geoIPDatabases,
+		"geoip-db",
+		"",
+		"Comma-separated paths of MaxMind-format .mmdb files (e.g. GeoLite2-Country and GeoLite2-ASN) "+
+			"used to export cluster node geography. Disabled if empty",
+	)
+	flag.StringVar(
+		&identities,
+		"identities",
+		"",
+		"Comma-separated identity pubkeys to check for duplicate gossip entries, "+
+			"along with the RPC node's own identity. Disabled if empty",
+	)
+	flag.StringVar(
+		&ledgerPath,
+		"ledger-path",
+		"",
+		"Ledger directory of a co-located node, enables snapshot and disk space inspection. Disabled if empty",
+	)
+	flag.StringVar(
+		&accountsPath,
+		"accounts-path",
+		"",
+		"Accounts directory of the co-located node, if not <ledger-path>/accounts",
+	)
+	flag.StringVar(
+		&snapshotsPath,
+		"snapshots-path",
+		"",
+		"Snapshot archive directory of the co-located node, if not <ledger-path>",
+	)
+	flag.BoolVar(
+		&discoverLocal,
+		"discover-local",
+		false,
+		"Find a running agave-validator or solana-validator process and derive the RPC URL, network, "+
+			"ledger, accounts and snapshots paths from its arguments. Explicitly set flags take precedence",
+	)
+	flag.BoolVar(
+		&safeToRestart,
+		"safe-to-restart",
+		false,
+		"Serve /api/v1/safe-to-restart and export the inputs of its decision, evaluated on every request and scrape",
+	)
+	flag.StringVar(
+		&restartPeers,
+		"restart-peers",
+		"",
+		"Comma-separated RPC URLs of the other fleet nodes whose health /api/v1/safe-to-restart checks",
+	)
+	flag.IntVar(
+		&restartRules.MinHealthyPeers,
+		"restart-min-healthy-peers",
+		0,
+		"Minimum number of --restart-peers that must be healthy for /api/v1/safe-to-restart to allow a restart. "+
+			"Not checked if 0",
+	)
+	flag.Int64Var(
+		&restartRules.LeaderMarginSlots,
+		"restart-leader-margin",
+		300,
+		"Slots before the node's next leader slot within which /api/v1/safe-to-restart denies a restart",
+	)
+	flag.Int64Var(
+		&restartRules.MaxSnapshotAgeSlots,
+		"restart-max-snapshot-age",
+		50000,
+		"Maximum age in slots of the newest local snapshot for /api/v1/safe-to-restart to allow a restart",
+	)
+	flag.Int64Var(
+		&restartRules.EpochMarginSlots,
+		"restart-epoch-margin",
+		1500,
+		"Slots before the next epoch boundary within which /api/v1/safe-to-restart denies a restart",
+	)
+	flag.StringVar(
+		&squadsMultisigs,
+		"squads-multisigs",
+		"",
+		"Comma-separated Squads v4 multisig addresses whose pending proposals are monitored. Disabled if empty",
+	)
+	flag.BoolVar(
+		&blockLatency,
+		"block-latency",
+		false,
+		"Fetch every new confirmed block (without transactions) to measure how late blocks arrive at the node",
+	)
+	flag.BoolVar(
+		&rollbackDetection,
+		"rollback-detection",
+		false,
+		"Remember the blockhash of every confirmed block and check it again at finalized commitment",
+	)
+	flag.StringVar(
+		&recordingRules,
+		"recording-rules",
+		"",
+		"YAML file of recording rules whose derived series are evaluated and exported on every scrape. Disabled if empty",
+	)
+	flag.StringVar(
+		&apyStakeAccounts,
+		"apy-stake-accounts",
+		"",
+		"Comma-separated stake accounts whose inflation rewards are used to estimate the staking APY "+
+			"of the validators they are delegated to. Disabled if empty",
+	)
+	flag.IntVar(
+		&apyEpochs,
+		"apy-epochs",
+		5,
+		"Number of most recent completed epochs the staking APY is estimated over",
+	)
+	flag.BoolVar(
+		&priorityFeeAPI,
+		"priority-fee-api",
+		false,
+		"Serve priority fee recommendations from cached getRecentPrioritizationFees results on /api/v1/priority-fee",
+	)
+	flag.Int64Var(
+		&priorityFeeLimits.Floor,
+		"priority-fee-floor",
+		0,
+		"Lowest price in micro-lamports per compute unit recommended by /api/v1/priority-fee",
+	)
+	flag.Int64Var(
+		&priorityFeeLimits.Ceiling,
+		"priority-fee-ceiling",
+		0,
+		"Highest price in micro-lamports per compute unit recommended by /api/v1/priority-fee. Unbounded if 0",
+	)
+	flag.StringVar(
+		&versionPolicy,
+		"version-policy",
+		"",
+		"YAML file of minimum and blocked solana-core versions per network the node's version is checked against. "+
+			"Disabled if empty",
+	)
+	flag.StringVar(
+		&auditEndpoints,
+		"audit-endpoints",
+		"",
+		"Comma-separated RPC URLs whose public exposure (sensitive methods, CORS, TLS, WebSocket port) is audited "+
+			"every 10 minutes. Disabled if empty",
+	)
+	flag.StringVar(
+		&airdropProbe.Address,
+		"airdrop-address",
+		"",
+		"Address that is periodically sent an airdrop to probe the faucet of devnet, testnet or a local "+
+			"validator. Refused on mainnet-beta. Disabled if empty",
+	)
+	flag.Int64Var(
+		&airdropProbe.Lamports,
+		"airdrop-lamports",
+		1_000_000,
+		"Lamports requested by each airdrop probe",
+	)
+	flag.IntVar(
+		&airdropInterval,
+		"airdrop-interval",
+		1800,
+		"Time between airdrop probes in seconds",
+	)
+	flag.StringVar(
+		&delegationVotes,
+		"delegation-vote-accounts",
+		"",
+		"Comma-separated vote accounts whose delegated stake accounts are listed every 10 minutes to track "+
+			"active, activating and deactivating stake. Disabled if empty",
+	)
+	flag.Int64Var(
+		&delegationEvent,
+		"delegation-event-sol",
+		10000,
+		"Size in SOL from which a delegation starting to activate or deactivate, or a change in a tracked "+
+			"validator's stake between epochs, is reported as an event",
+	)
+	flag.StringVar(
+		&tokenMints,
+		"token-mints",
+		"",
+		"Comma-separated token mints whose transfer volume is counted in the newest confirmed block every 5 seconds, "+
+			"fetched with full transaction details. Disabled if empty",
+	)
+	flag.BoolVar(
+		&nativeHealth,
+		"native-health",
+		false,
+		"Also probe the node's GET /health endpoint used by load balancers, and flag disagreements with getHealth",
+	)
 	flag.Parse()
 
+	if discoverLocal {
+		node, err := DiscoverLocalNode(DefaultProcPath)
+		if err != nil {
+			return nil, err
+		}
+		slog.Get().Infof("Discovered local node: %s", node)
+		if err := applyDiscoveredDefaults(flag.CommandLine, node); err != nil {
+			return nil, err
+		}
+	}
+
+	airdropProbe.Interval = time.Duration(airdropInterval) * time.Second
 	config, err := NewExporterConfig(
 		ctx,
 		time.Duration(httpTimeout)*time.Second,
@@ -104,9 +460,41 @@ func NewExporterConfigFromCLI(ctx context.Context) (*ExporterConfig, error) {
 		time.Duration(slotPace)*time.Second,
 		networkName,
 		debug,
+		rpcMaxConcurrency,
+		splitList(geoIPDatabases),
+		splitList(identities),
+		ledgerPath,
+		accountsPath,
+		snapshotsPath,
+		safeToRestart,
+		splitList(restartPeers),
+		restartRules,
+		splitList(squadsMultisigs),
+		blockLatency,
+		rollbackDetection,
+		recordingRules,
+		splitList(apyStakeAccounts),
+		apyEpochs,
+		priorityFeeAPI,
+		priorityFeeLimits,
+		versionPolicy,
+		splitList(auditEndpoints),
+		airdropProbe,
+		splitList(delegationVotes),
+		delegationEvent,
+		splitList(tokenMints),
+		nativeHealth,
 	)
 	if err != nil {
 		return nil, err
 	}
 	return config, nil
 }
+
+// snapshotArchivePath is where snapshot archives are written, the ledger directory unless overridden
+func (c *ExporterConfig) snapshotArchivePath() string {
+	if c.SnapshotsPath != "" {
+		return c.SnapshotsPath
+	}
+	return c.LedgerPath
+}
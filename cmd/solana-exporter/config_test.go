@@ -17,6 +17,30 @@ func TestNewExporterConfig(t *testing.T) {
 		slotPace      time.Duration
 		networkName   string
 		debug         bool
+		maxConcurrent int
+		geoIPDbs      []string
+		identities    []string
+		ledgerPath    string
+		accountsPath  string
+		snapshotsPath string
+		safeRestart   bool
+		restartPeers  []string
+		restartRules  RestartRules
+		multisigs     []string
+		blockLatency  bool
+		rollbacks     bool
+		rulesPath     string
+		stakeAccounts []string
+		apyEpochs     int
+		feeAPI        bool
+		feeLimits     PriorityFeeLimits
+		policyPath    string
+		audited       []string
+		airdrop       AirdropProbe
+		delegations   []string
+		eventSol      int64
+		mints         []string
+		nativeHealth  bool
 		wantErr       bool
 	}{
 		{
@@ -27,6 +51,8 @@ func TestNewExporterConfig(t *testing.T) {
 			slotPace:      time.Second,
 			networkName:   "mainnet-beta",
 			debug:         false,
+			maxConcurrent: 8,
+			apyEpochs:     5,
 			wantErr:       false,
 		},
 		{
@@ -37,6 +63,29 @@ func TestNewExporterConfig(t *testing.T) {
 			slotPace:      2 * time.Second,
 			networkName:   "testnet",
 			debug:         true,
+			maxConcurrent: 2,
+			geoIPDbs:      []string{"/data/GeoLite2-Country.mmdb", "/data/GeoLite2-ASN.mmdb"},
+			identities:    []string{"nodeA", "nodeB"},
+			ledgerPath:    "/mnt/ledger",
+			accountsPath:  "/mnt/accounts",
+			snapshotsPath: "/mnt/snapshots",
+			safeRestart:   true,
+			restartPeers:  []string{"http://peerA:8899", "http://peerB:8899"},
+			restartRules:  RestartRules{LeaderMarginSlots: 100, MaxSnapshotAgeSlots: 25000, EpochMarginSlots: 500, MinHealthyPeers: 1},
+			multisigs:     []string{"multisigA"},
+			blockLatency:  true,
+			rollbacks:     true,
+			rulesPath:     "/etc/solana-exporter/rules.yaml",
+			stakeAccounts: []string{"stakeA", "stakeB"},
+			apyEpochs:     10,
+			feeAPI:        true,
+			feeLimits:     PriorityFeeLimits{Floor: 1_000, Ceiling: 2_000_000},
+			policyPath:    "/etc/solana-exporter/versions.yaml",
+			audited:       []string{"https://rpc.example.com"},
+			delegations:   []string{"voteA"},
+			eventSol:      5_000,
+			mints:         []string{"mintA", "mintB"},
+			nativeHealth:  true,
 			wantErr:       false,
 		},
 		{
@@ -47,8 +96,71 @@ func TestNewExporterConfig(t *testing.T) {
 			slotPace:      500 * time.Millisecond,
 			networkName:   "devnet",
 			debug:         false,
+			maxConcurrent: 0,
+			apyEpochs:     1,
+			airdrop:       AirdropProbe{Address: "addressA", Lamports: 1, Interval: time.Minute},
 			wantErr:       false,
 		},
+		{
+			name:          "no apy epochs",
+			httpTimeout:   60 * time.Second,
+			rpcUrl:        "http://localhost:8899",
+			listenAddress: ":8080",
+			slotPace:      time.Second,
+			networkName:   "mainnet-beta",
+			maxConcurrent: 8,
+			apyEpochs:     0,
+			wantErr:       true,
+		},
+		{
+			name:          "priority fee ceiling below floor",
+			httpTimeout:   60 * time.Second,
+			rpcUrl:        "http://localhost:8899",
+			listenAddress: ":8080",
+			slotPace:      time.Second,
+			networkName:   "mainnet-beta",
+			maxConcurrent: 8,
+			apyEpochs:     5,
+			feeLimits:     PriorityFeeLimits{Floor: 1_000, Ceiling: 500},
+			wantErr:       true,
+		},
+		{
+			name:          "more healthy peers required than configured",
+			httpTimeout:   60 * time.Second,
+			rpcUrl:        "http://localhost:8899",
+			listenAddress: ":8080",
+			slotPace:      time.Second,
+			networkName:   "mainnet-beta",
+			maxConcurrent: 8,
+			apyEpochs:     5,
+			restartPeers:  []string{"http://peerA:8899"},
+			restartRules:  RestartRules{MinHealthyPeers: 2},
+			wantErr:       true,
+		},
+		{
+			name:          "airdrop probe on mainnet-beta",
+			httpTimeout:   60 * time.Second,
+			rpcUrl:        "http://localhost:8899",
+			listenAddress: ":8080",
+			slotPace:      time.Second,
+			networkName:   "mainnet-beta",
+			maxConcurrent: 8,
+			apyEpochs:     5,
+			airdrop:       AirdropProbe{Address: "addressA", Lamports: 1_000_000, Interval: time.Hour},
+			wantErr:       true,
+		},
+		{
+			name:          "airdrop probe without lamports",
+			httpTimeout:   60 * time.Second,
+			rpcUrl:        "http://localhost:8899",
+			listenAddress: ":8080",
+			slotPace:      time.Second,
+			networkName:   "devnet",
+			maxConcurrent: 8,
+			apyEpochs:     5,
+			airdrop:       AirdropProbe{Address: "addressA", Interval: time.Hour},
+			wantErr:       true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -61,6 +173,30 @@ func TestNewExporterConfig(t *testing.T) {
 				tt.slotPace,
 				tt.networkName,
 				tt.debug,
+				tt.maxConcurrent,
+				tt.geoIPDbs,
+				tt.identities,
+				tt.ledgerPath,
+				tt.accountsPath,
+				tt.snapshotsPath,
+				tt.safeRestart,
+				tt.restartPeers,
+				tt.restartRules,
+				tt.multisigs,
+				tt.blockLatency,
+				tt.rollbacks,
+				tt.rulesPath,
+				tt.stakeAccounts,
+				tt.apyEpochs,
+				tt.feeAPI,
+				tt.feeLimits,
+				tt.policyPath,
+				tt.audited,
+				tt.airdrop,
+				tt.delegations,
+				tt.eventSol,
+				tt.mints,
+				tt.nativeHealth,
 			)
 
 			if tt.wantErr {
@@ -75,6 +211,30 @@ func TestNewExporterConfig(t *testing.T) {
 			assert.Equal(t, tt.slotPace, config.SlotPace)
 			assert.Equal(t, tt.networkName, config.NetworkName)
 			assert.Equal(t, tt.debug, config.Debug)
+			assert.Equal(t, tt.maxConcurrent, config.RpcMaxConcurrency)
+			assert.Equal(t, tt.geoIPDbs, config.GeoIPDatabases)
+			assert.Equal(t, tt.identities, config.Identities)
+			assert.Equal(t, tt.ledgerPath, config.LedgerPath)
+			assert.Equal(t, tt.accountsPath, config.AccountsPath)
+			assert.Equal(t, tt.snapshotsPath, config.SnapshotsPath)
+			assert.Equal(t, tt.safeRestart, config.SafeToRestart)
+			assert.Equal(t, tt.restartPeers, config.RestartPeers)
+			assert.Equal(t, tt.restartRules, config.RestartRules)
+			assert.Equal(t, tt.multisigs, config.SquadsMultisigs)
+			assert.Equal(t, tt.blockLatency, config.BlockLatency)
+			assert.Equal(t, tt.rollbacks, config.RollbackDetection)
+			assert.Equal(t, tt.rulesPath, config.RecordingRules)
+			assert.Equal(t, tt.stakeAccounts, config.ApyStakeAccounts)
+			assert.Equal(t, tt.apyEpochs, config.ApyEpochs)
+			assert.Equal(t, tt.feeAPI, config.PriorityFeeAPI)
+			assert.Equal(t, tt.feeLimits, config.PriorityFeeLimits)
+			assert.Equal(t, tt.policyPath, config.VersionPolicy)
+			assert.Equal(t, tt.audited, config.AuditEndpoints)
+			assert.Equal(t, tt.airdrop, config.AirdropProbe)
+			assert.Equal(t, tt.delegations, config.DelegationVoteAccounts)
+			assert.Equal(t, tt.eventSol, config.DelegationEventSol)
+			assert.Equal(t, tt.mints, config.TokenMints)
+			assert.Equal(t, tt.nativeHealth, config.NativeHealth)
 		})
 	}
 }
@@ -89,6 +249,31 @@ func parseFlagsForTest(args []string) (*ExporterConfig, error) {
 		slotPace      int
 		networkName   string
 		debug         bool
+		maxConcurrent int
+		geoIPDbs      string
+		identities    string
+		ledgerPath    string
+		accountsPath  string
+		snapshotsPath string
+		safeRestart   bool
+		restartPeers  string
+		restartRules  RestartRules
+		multisigs     string
+		blockLatency  bool
+		rollbacks     bool
+		rulesPath     string
+		stakeAccounts string
+		apyEpochs     int
+		feeAPI        bool
+		feeLimits     PriorityFeeLimits
+		policyPath    string
+		audited       string
+		airdrop       AirdropProbe
+		airdropPace   int
+		delegations   string
+		eventSol      int64
+		mints         string
+		nativeHealth  bool
 	)
 
 	flagSet.IntVar(&httpTimeout, "http-timeout", 60, "HTTP timeout in seconds")
@@ -97,11 +282,42 @@ func parseFlagsForTest(args []string) (*ExporterConfig, error) {
 	flagSet.IntVar(&slotPace, "slot-pace", 1, "Slot pace in seconds")
 	flagSet.StringVar(&networkName, "network", "mainnet-beta", "Network name")
 	flagSet.BoolVar(&debug, "debug", false, "Enable debug mode")
+	flagSet.IntVar(&maxConcurrent, "rpc-max-concurrency", 8, "Maximum concurrent RPC calls")
+	flagSet.StringVar(&geoIPDbs, "geoip-db", "", "GeoIP database paths")
+	flagSet.StringVar(&identities, "identities", "", "Identities to watch")
+	flagSet.StringVar(&ledgerPath, "ledger-path", "", "Ledger directory")
+	flagSet.StringVar(&accountsPath, "accounts-path", "", "Accounts directory")
+	flagSet.StringVar(&snapshotsPath, "snapshots-path", "", "Snapshot archive directory")
+	flagSet.BoolVar(&safeRestart, "safe-to-restart", false, "Serve the safe-to-restart endpoint")
+	flagSet.StringVar(&restartPeers, "restart-peers", "", "Fleet peers checked before a restart")
+	flagSet.IntVar(&restartRules.MinHealthyPeers, "restart-min-healthy-peers", 0, "Minimum healthy peers")
+	flagSet.Int64Var(&restartRules.LeaderMarginSlots, "restart-leader-margin", 300, "Leader slot margin")
+	flagSet.Int64Var(&restartRules.MaxSnapshotAgeSlots, "restart-max-snapshot-age", 50000, "Maximum snapshot age")
+	flagSet.Int64Var(&restartRules.EpochMarginSlots, "restart-epoch-margin", 1500, "Epoch boundary margin")
+	flagSet.StringVar(&multisigs, "squads-multisigs", "", "Squads multisigs to monitor")
+	flagSet.BoolVar(&blockLatency, "block-latency", false, "Measure block arrival latency")
+	flagSet.BoolVar(&rollbacks, "rollback-detection", false, "Detect rollbacks of confirmed blocks")
+	flagSet.StringVar(&rulesPath, "recording-rules", "", "Recording rules file")
+	flagSet.StringVar(&stakeAccounts, "apy-stake-accounts", "", "Reference stake accounts")
+	flagSet.IntVar(&apyEpochs, "apy-epochs", 5, "Epochs to estimate the staking APY over")
+	flagSet.BoolVar(&feeAPI, "priority-fee-api", false, "Serve priority fee recommendations")
+	flagSet.Int64Var(&feeLimits.Floor, "priority-fee-floor", 0, "Lowest recommended priority fee")
+	flagSet.Int64Var(&feeLimits.Ceiling, "priority-fee-ceiling", 0, "Highest recommended priority fee")
+	flagSet.StringVar(&policyPath, "version-policy", "", "Version policy file")
+	flagSet.StringVar(&audited, "audit-endpoints", "", "RPC URLs to audit")
+	flagSet.StringVar(&airdrop.Address, "airdrop-address", "", "Airdrop probe address")
+	flagSet.Int64Var(&airdrop.Lamports, "airdrop-lamports", 1_000_000, "Lamports per airdrop probe")
+	flagSet.IntVar(&airdropPace, "airdrop-interval", 1800, "Airdrop probe interval in seconds")
+	flagSet.StringVar(&delegations, "delegation-vote-accounts", "", "Vote accounts to track delegations of")
+	flagSet.Int64Var(&eventSol, "delegation-event-sol", 10000, "Delegation event threshold in SOL")
+	flagSet.StringVar(&mints, "token-mints", "", "Token mints to count transfers of")
+	flagSet.BoolVar(&nativeHealth, "native-health", false, "Probe GET /health")
 
 	if err := flagSet.Parse(args); err != nil {
 		return nil, err
 	}
 
+	airdrop.Interval = time.Duration(airdropPace) * time.Second
 	return NewExporterConfig(
 		context.Background(),
 		time.Duration(httpTimeout)*time.Second,
@@ -110,6 +326,30 @@ func parseFlagsForTest(args []string) (*ExporterConfig, error) {
 		time.Duration(slotPace)*time.Second,
 		networkName,
 		debug,
+		maxConcurrent,
+		splitList(geoIPDbs),
+		splitList(identities),
+		ledgerPath,
+		accountsPath,
+		snapshotsPath,
+		safeRestart,
+		splitList(restartPeers),
+		restartRules,
+		splitList(multisigs),
+		blockLatency,
+		rollbacks,
+		rulesPath,
+		splitList(stakeAccounts),
+		apyEpochs,
+		feeAPI,
+		feeLimits,
+		policyPath,
+		splitList(audited),
+		airdrop,
+		splitList(delegations),
+		eventSol,
+		splitList(mints),
+		nativeHealth,
 	)
 }
 
@@ -130,6 +370,28 @@ func TestNewExporterConfigFromCLI(t *testing.T) {
 				assert.Equal(t, time.Second, config.SlotPace)
 				assert.Equal(t, "mainnet-beta", config.NetworkName)
 				assert.False(t, config.Debug)
+				assert.Equal(t, 8, config.RpcMaxConcurrency)
+				assert.Empty(t, config.GeoIPDatabases)
+				assert.Empty(t, config.Identities)
+				assert.Empty(t, config.LedgerPath)
+				assert.False(t, config.SafeToRestart)
+				assert.Empty(t, config.RestartPeers)
+				assert.Empty(t, config.SquadsMultisigs)
+				assert.False(t, config.BlockLatency)
+				assert.False(t, config.RollbackDetection)
+				assert.Empty(t, config.RecordingRules)
+				assert.Empty(t, config.ApyStakeAccounts)
+				assert.Equal(t, 5, config.ApyEpochs)
+				assert.False(t, config.PriorityFeeAPI)
+				assert.Equal(t, PriorityFeeLimits{}, config.PriorityFeeLimits)
+				assert.Empty(t, config.VersionPolicy)
+				assert.Empty(t, config.AuditEndpoints)
+				assert.Equal(t, AirdropProbe{Lamports: 1_000_000, Interval: 30 * time.Minute}, config.AirdropProbe)
+				assert.Empty(t, config.DelegationVoteAccounts)
+				assert.Equal(t, int64(10000), config.DelegationEventSol)
+				assert.Empty(t, config.TokenMints)
+				assert.False(t, config.NativeHealth)
+				assert.Equal(t, RestartRules{LeaderMarginSlots: 300, MaxSnapshotAgeSlots: 50000, EpochMarginSlots: 1500}, config.RestartRules)
 			},
 		},
 		{
@@ -141,6 +403,24 @@ func TestNewExporterConfigFromCLI(t *testing.T) {
 				"-slot-pace", "2",
 				"-network", "testnet",
 				"-debug",
+				"-rpc-max-concurrency", "4",
+				"-geoip-db", "/data/country.mmdb, /data/asn.mmdb",
+				"-identities", "nodeA",
+				"-ledger-path", "/mnt/ledger",
+				"-safe-to-restart",
+				"-restart-peers", "http://peerA:8899,http://peerB:8899",
+				"-restart-min-healthy-peers", "1",
+				"-restart-leader-margin", "50",
+				"-block-latency",
+				"-apy-stake-accounts", "stakeA",
+				"-apy-epochs", "3",
+				"-priority-fee-api",
+				"-priority-fee-floor", "100",
+				"-airdrop-address", "addressA",
+				"-airdrop-interval", "600",
+				"-delegation-vote-accounts", "voteA,voteB",
+				"-token-mints", "mintA",
+				"-native-health",
 			},
 			validate: func(t *testing.T, config *ExporterConfig) {
 				assert.Equal(t, 30*time.Second, config.HttpTimeout)
@@ -149,6 +429,24 @@ func TestNewExporterConfigFromCLI(t *testing.T) {
 				assert.Equal(t, 2*time.Second, config.SlotPace)
 				assert.Equal(t, "testnet", config.NetworkName)
 				assert.True(t, config.Debug)
+				assert.Equal(t, 4, config.RpcMaxConcurrency)
+				assert.Equal(t, []string{"/data/country.mmdb", "/data/asn.mmdb"}, config.GeoIPDatabases)
+				assert.Equal(t, []string{"nodeA"}, config.Identities)
+				assert.Equal(t, "/mnt/ledger", config.LedgerPath)
+				assert.Equal(t, "/mnt/ledger", config.snapshotArchivePath())
+				assert.True(t, config.SafeToRestart)
+				assert.Equal(t, []string{"http://peerA:8899", "http://peerB:8899"}, config.RestartPeers)
+				assert.Equal(t, 1, config.RestartRules.MinHealthyPeers)
+				assert.Equal(t, int64(50), config.RestartRules.LeaderMarginSlots)
+				assert.True(t, config.BlockLatency)
+				assert.Equal(t, []string{"stakeA"}, config.ApyStakeAccounts)
+				assert.Equal(t, 3, config.ApyEpochs)
+				assert.True(t, config.PriorityFeeAPI)
+				assert.Equal(t, PriorityFeeLimits{Floor: 100}, config.PriorityFeeLimits)
+				assert.Equal(t, AirdropProbe{Address: "addressA", Lamports: 1_000_000, Interval: 10 * time.Minute}, config.AirdropProbe)
+				assert.Equal(t, []string{"voteA", "voteB"}, config.DelegationVoteAccounts)
+				assert.Equal(t, []string{"mintA"}, config.TokenMints)
+				assert.True(t, config.NativeHealth)
 			},
 		},
 	}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/naviat/solana-rpc-exporter/pkg/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	StakeStateLabel = "state"
+	DirectionLabel  = "direction"
+
+	// StakeActive is effective stake that is not being deactivated
+	StakeActive = "active"
+	// StakeActivating is stake delegated in the current epoch, effective from the next one
+	StakeActivating = "activating"
+	// StakeDeactivating is effective stake that stops being effective in the next epoch
+	StakeDeactivating = "deactivating"
+
+	// DelegationIncoming is a large delegation that started activating
+	DelegationIncoming = "incoming"
+	// DelegationDeparting is a large delegation that started deactivating
+	DelegationDeparting = "departing"
+
+	// DelegationInterval is how often the stake accounts of the tracked vote accounts are listed
+	DelegationInterval = 10 * time.Minute
+	// stakeWithdrawerOffset is the offset of the withdraw authority, after the state tag, the
+	// rent reserve and the stake authority
+	stakeWithdrawerOffset = 44
+	// stakeAmountOffset is the offset of the delegated lamports, followed by the activation and
+	// deactivation epochs
+	stakeAmountOffset = stakeVoterOffset + 32
+	// noEpoch is the activation epoch of genesis stake and the deactivation epoch of stake that
+	// is not deactivating
+	noEpoch = math.MaxUint64
+)
+
+type (
+	// StakeDelegation is the delegation of a stake account
+	StakeDelegation struct {
+		Voter      string
+		Withdrawer string
+		Stake      int64
+		// ActivationEpoch and DeactivationEpoch are noEpoch if unset
+		ActivationEpoch   uint64
+		DeactivationEpoch uint64
+	}
+
+	// ValidatorDelegations summarizes the stake accounts delegated to one vote account
+	ValidatorDelegations struct {
+		// Stake holds lamports by StakeActive, StakeActivating and StakeDeactivating
+		Stake         map[string]int64
+		StakeAccounts int
+		// Delegators is the number of distinct withdraw authorities, so a stake pool counts once
+		Delegators int
+		// EpochChange is the change in effective stake since the previous epoch, nil until an
+		// epoch boundary has been seen
+		EpochChange *int64
+	}
+
+	// epochStake is the effective stake of a vote account when an epoch was first seen
+	epochStake struct {
+		epoch     int64
+		effective int64
+		// change since the previous epoch seen, nil for the first one
+		change *int64
+	}
+
+	// DelegationWatcher lists the stake accounts delegated to the configured vote accounts, giving
+	// operators early warning of incoming and departing delegations that getVoteAccounts only
+	// shows once they are effective.
+	//
+	// Activation and deactivation are assumed to complete within one epoch. That holds unless the
+	// cluster-wide stake changing in an epoch exceeds the warmup and cooldown rate, in which case
+	// the remainder is counted as active or inactive early.
+	DelegationWatcher struct {
+		client *rpc.Client
+		logger *zap.SugaredLogger
+		config *ExporterConfig
+
+		// only touched by the watching goroutine
+		epochStakes map[string]epochStake
+		// announced holds the state each large delegation was last announced in, by vote account
+		// and stake account
+		announced map[string]map[string]string
+
+		mu         sync.RWMutex
+		validators map[string]*ValidatorDelegations
+
+		DelegatedStake   *GaugeDesc
+		StakeAccounts    *GaugeDesc
+		Delegators       *GaugeDesc
+		EpochStakeChange *GaugeDesc
+		LargeChanges     *prometheus.CounterVec
+	}
+)
+
+// DecodeStakeDelegation decodes the delegation of a stake account
+func DecodeStakeDelegation(data []byte) (*StakeDelegation, error) {
+	if len(data) < stakeAmountOffset+24 {
+		return nil, errors.New("account data too short")
+	}
+	if state := binary.LittleEndian.Uint32(data); state != stakeStateDelegated {
+		return nil, fmt.Errorf("stake account is not delegated (state %d)", state)
+	}
+	return &StakeDelegation{
+		Voter:             encodeBase58(data[stakeVoterOffset : stakeVoterOffset+32]),
+		Withdrawer:        encodeBase58(data[stakeWithdrawerOffset : stakeWithdrawerOffset+32]),
+		Stake:             int64(binary.LittleEndian.Uint64(data[stakeAmountOffset:])),
+		ActivationEpoch:   binary.LittleEndian.Uint64(data[stakeAmountOffset+8:]),
+		DeactivationEpoch: binary.LittleEndian.Uint64(data[stakeAmountOffset+16:]),
+	}, nil
+}
+
+// State returns the state of the delegation in epoch, or "" if its stake is inactive
+func (d *StakeDelegation) State(epoch int64) string {
+	current := uint64(epoch)
+	switch {
+	case d.ActivationEpoch != noEpoch && d.ActivationEpoch == d.DeactivationEpoch:
+		// deactivated in the epoch it was delegated, so it never became effective
+		return ""
+	case d.DeactivationEpoch < current:
+		return ""
+	case d.DeactivationEpoch == current:
+		return StakeDeactivating
+	case d.ActivationEpoch == noEpoch || d.ActivationEpoch < current:
+		return StakeActive
+	case d.ActivationEpoch == current:
+		return StakeActivating
+	}
+	return ""
+}
+
+// effective is the stake counted by the cluster in the current epoch
+func (v *ValidatorDelegations) effective() int64 {
+	return v.Stake[StakeActive] + v.Stake[StakeDeactivating]
+}
+
+func NewDelegationWatcher(client *rpc.Client, config *ExporterConfig) *DelegationWatcher {
+	return &DelegationWatcher{
+		client:      client,
+		logger:      slog.Get(),
+		config:      config,
+		epochStakes: make(map[string]epochStake),
+		announced:   make(map[string]map[string]string),
+		validators:  make(map[string]*ValidatorDelegations),
+
+		DelegatedStake: NewGaugeDesc(
+			"solana_validator_delegated_stake_lamports",
+			fmt.Sprintf(
+				"Stake delegated to the vote account, by %s (%s, %s or %s)",
+				StakeStateLabel, StakeActive, StakeActivating, StakeDeactivating,
+			),
+			NetworkLabel, VoteAccountLabel, StakeStateLabel,
+		),
+		StakeAccounts: NewGaugeDesc(
+			"solana_validator_stake_accounts",
+			"Number of stake accounts with active, activating or deactivating stake delegated to the vote account",
+			NetworkLabel, VoteAccountLabel,
+		),
+		Delegators: NewGaugeDesc(
+			"solana_validator_delegators",
+			"Number of distinct withdraw authorities of the stake accounts delegated to the vote account",
+			NetworkLabel, VoteAccountLabel,
+		),
+		EpochStakeChange: NewGaugeDesc(
+			"solana_validator_epoch_stake_change_lamports",
+			"Change in the vote account's effective stake between the previous epoch and the current one",
+			NetworkLabel, VoteAccountLabel,
+		),
+		LargeChanges: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "solana_validator_large_delegation_changes_total",
+				Help: fmt.Sprintf(
+					"Delegations of at least the event threshold that started activating (%s) or deactivating (%s)",
+					DelegationIncoming, DelegationDeparting,
+				),
+			},
+			[]string{NetworkLabel, VoteAccountLabel, DirectionLabel},
+		),
+	}
+}
+
+func (w *DelegationWatcher) WatchDelegations(ctx context.Context) error {
+	ticker := time.NewTicker(DelegationInterval)
+	defer ticker.Stop()
+
+	bulkCtx := rpc.WithPriority(ctx, rpc.PriorityBulk)
+	for {
+		if err := w.update(bulkCtx); err != nil {
+			w.logger.Errorw("Failed to track delegations", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// update lists the stake accounts of every tracked vote account; a vote account whose listing
+// fails keeps its previous state
+func (w *DelegationWatcher) update(ctx context.Context) error {
+	epochInfo, err := w.client.GetEpochInfo(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		return fmt.Errorf("failed to get epoch info: %w", err)
+	}
+
+	for _, voteAccount := range w.config.DelegationVoteAccounts {
+		accounts, err := w.client.GetProgramAccounts(ctx, rpc.CommitmentConfirmed, StakeProgramID, []rpc.ProgramAccountFilter{
+			{Memcmp: &rpc.MemcmpFilter{Offset: stakeVoterOffset, Bytes: voteAccount}},
+		})
+		if err != nil {
+			w.logger.Errorw("Failed to list stake accounts", "vote_account", voteAccount, "error", err)
+			continue
+		}
+		delegations := w.tally(voteAccount, accounts, epochInfo.Epoch)
+
+		w.mu.Lock()
+		w.validators[voteAccount] = delegations
+		w.mu.Unlock()
+	}
+	return nil
+}
+
+// tally summarizes the stake accounts of a vote account, announcing large delegations that
+// started activating or deactivating and the stake change since the previous epoch
+func (w *DelegationWatcher) tally(voteAccount string, accounts []rpc.ProgramAccount, epoch int64) *ValidatorDelegations {
+	threshold := w.config.DelegationEventSol * rpc.LamportsInSol
+	delegations := &ValidatorDelegations{Stake: map[string]int64{StakeActive: 0, StakeActivating: 0, StakeDeactivating: 0}}
+	withdrawers := make(map[string]bool)
+	announced := make(map[string]string)
+	for _, account := range accounts {
+		delegation, err := DecodeStakeDelegation(account.Account.Data)
+		if err != nil || delegation.Voter != voteAccount {
+			// initialized but undelegated accounts can match the filter by chance
+			continue
+		}
+		state := delegation.State(epoch)
+		if state == "" {
+			continue
+		}
+		delegations.Stake[state] += delegation.Stake
+		delegations.StakeAccounts++
+		withdrawers[delegation.Withdrawer] = true
+
+		if state == StakeActive || delegation.Stake < threshold {
+			continue
+		}
+		announced[account.Pubkey] = state
+		if w.announced[voteAccount][account.Pubkey] == state {
+			continue
+		}
+		direction := DelegationIncoming
+		if state == StakeDeactivating {
+			direction = DelegationDeparting
+		}
+		w.LargeChanges.WithLabelValues(w.config.NetworkName, voteAccount, direction).Inc()
+		w.logger.Warnw(
+			"Large delegation change", "vote_account", voteAccount, "stake_account", account.Pubkey,
+			"direction", direction, "sol", float64(delegation.Stake)/rpc.LamportsInSol, "epoch", epoch,
+		)
+	}
+	delegations.Delegators = len(withdrawers)
+	w.announced[voteAccount] = announced
+
+	// the change is measured from the first sample of each epoch, and kept for the whole epoch
+	current, ok := w.epochStakes[voteAccount]
+	if !ok || current.epoch < epoch {
+		previous := current
+		current = epochStake{epoch: epoch, effective: delegations.effective()}
+		if ok {
+			change := current.effective - previous.effective
+			current.change = &change
+			if change >= threshold || -change >= threshold {
+				w.logger.Warnw(
+					"Large stake change between epochs", "vote_account", voteAccount, "epoch", epoch,
+					"sol", float64(change)/rpc.LamportsInSol,
+				)
+			}
+		}
+		w.epochStakes[voteAccount] = current
+	}
+	delegations.EpochChange = current.change
+	return delegations
+}
+
+func (w *DelegationWatcher) Describe(ch chan<- *prometheus.Desc) {
+	ch <- w.DelegatedStake.Desc
+	ch <- w.StakeAccounts.Desc
+	ch <- w.Delegators.Desc
+	ch <- w.EpochStakeChange.Desc
+	w.LargeChanges.Describe(ch)
+}
+
+func (w *DelegationWatcher) Collect(ch chan<- prometheus.Metric) {
+	w.LargeChanges.Collect(ch)
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	for voteAccount, delegations := range w.validators {
+		for state, lamports := range delegations.Stake {
+			ch <- w.DelegatedStake.MustNewConstMetric(float64(lamports), w.config.NetworkName, voteAccount, state)
+		}
+		ch <- w.StakeAccounts.MustNewConstMetric(float64(delegations.StakeAccounts), w.config.NetworkName, voteAccount)
+		ch <- w.Delegators.MustNewConstMetric(float64(delegations.Delegators), w.config.NetworkName, voteAccount)
+		if delegations.EpochChange != nil {
+			ch <- w.EpochStakeChange.MustNewConstMetric(float64(*delegations.EpochChange), w.config.NetworkName, voteAccount)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// encodeDelegation builds a delegated stake account
+func encodeDelegation(voter, withdrawer []byte, sol int64, activation, deactivation uint64) []byte {
+	data := encodeStakeAccount(stakeStateDelegated, voter)
+	copy(data[stakeWithdrawerOffset:], withdrawer)
+	binary.LittleEndian.PutUint64(data[stakeAmountOffset:], uint64(sol*rpc.LamportsInSol))
+	binary.LittleEndian.PutUint64(data[stakeAmountOffset+8:], activation)
+	binary.LittleEndian.PutUint64(data[stakeAmountOffset+16:], deactivation)
+	return data
+}
+
+func TestDecodeStakeDelegation(t *testing.T) {
+	voter, withdrawer := bytes.Repeat([]byte{7}, 32), bytes.Repeat([]byte{8}, 32)
+	delegation, err := DecodeStakeDelegation(encodeDelegation(voter, withdrawer, 42, 500, noEpoch))
+	if assert.NoError(t, err) {
+		assert.Equal(t, &StakeDelegation{
+			Voter:             encodeBase58(voter),
+			Withdrawer:        encodeBase58(withdrawer),
+			Stake:             42 * rpc.LamportsInSol,
+			ActivationEpoch:   500,
+			DeactivationEpoch: noEpoch,
+		}, delegation)
+	}
+
+	_, err = DecodeStakeDelegation(encodeStakeAccount(1, voter))
+	assert.Error(t, err)
+	_, err = DecodeStakeDelegation(encodeStakeAccount(stakeStateDelegated, voter)[:160])
+	assert.Error(t, err)
+}
+
+func TestStakeDelegation_State(t *testing.T) {
+	tests := []struct {
+		name         string
+		activation   uint64
+		deactivation uint64
+		state        string
+	}{
+		{"active", 90, noEpoch, StakeActive},
+		{"genesis", noEpoch, noEpoch, StakeActive},
+		{"activating", 100, noEpoch, StakeActivating},
+		{"deactivating", 90, 100, StakeDeactivating},
+		{"deactivated", 90, 99, ""},
+		{"deactivated before activation", 100, 100, ""},
+		{"deactivating genesis", noEpoch, 100, StakeDeactivating},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			delegation := &StakeDelegation{ActivationEpoch: test.activation, DeactivationEpoch: test.deactivation}
+			assert.Equal(t, test.state, delegation.State(100))
+		})
+	}
+}
+
+func TestDelegationWatcher(t *testing.T) {
+	voter, other := bytes.Repeat([]byte{7}, 32), bytes.Repeat([]byte{9}, 32)
+	withdrawerA, withdrawerB := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	programAccount := func(pubkey string, data []byte) map[string]any {
+		return map[string]any{
+			"pubkey":  pubkey,
+			"account": map[string]any{"data": []string{base64.StdEncoding.EncodeToString(data), "base64"}, "owner": StakeProgramID},
+		}
+	}
+	server, client := rpc.NewMockClient(t, map[string]any{
+		"getEpochInfo": map[string]int{"epoch": 100},
+		"getProgramAccounts": []map[string]any{
+			programAccount("stakeA", encodeDelegation(voter, withdrawerA, 100, 50, noEpoch)),
+			programAccount("stakeB", encodeDelegation(voter, withdrawerA, 20, 100, noEpoch)),
+			programAccount("stakeC", encodeDelegation(voter, withdrawerB, 5, 40, 100)),
+			programAccount("stakeD", encodeDelegation(voter, withdrawerB, 30, 10, 90)),
+			programAccount("stakeE", encodeDelegation(other, withdrawerA, 50, 10, noEpoch)),
+			programAccount("garbage", []byte{1, 2, 3}),
+		},
+	})
+	voteAccount := encodeBase58(voter)
+	config := &ExporterConfig{NetworkName: "mainnet-beta", DelegationVoteAccounts: []string{voteAccount}, DelegationEventSol: 10}
+	watcher := NewDelegationWatcher(client, config)
+	ctx := context.Background()
+
+	// the second update in the same epoch announces nothing new
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, watcher.update(ctx))
+	}
+	delegations := watcher.validators[voteAccount]
+	if assert.NotNil(t, delegations) {
+		assert.Equal(t, map[string]int64{
+			StakeActive:       100 * rpc.LamportsInSol,
+			StakeActivating:   20 * rpc.LamportsInSol,
+			StakeDeactivating: 5 * rpc.LamportsInSol,
+		}, delegations.Stake)
+		assert.Equal(t, 3, delegations.StakeAccounts)
+		assert.Equal(t, 2, delegations.Delegators)
+		assert.Nil(t, delegations.EpochChange)
+	}
+	assert.Equal(t, 1.0, testutil.ToFloat64(watcher.LargeChanges.WithLabelValues("mainnet-beta", voteAccount, DelegationIncoming)))
+
+	// stakeB became effective and stakeC inactive
+	server.SetOpt(rpc.EasyResultsOpt, "getEpochInfo", map[string]int{"epoch": 101})
+	assert.NoError(t, watcher.update(ctx))
+
+	tests := []collectionTest{
+		watcher.DelegatedStake.makeCollectionTest(
+			NewLV(120*rpc.LamportsInSol, "mainnet-beta", StakeActive, voteAccount),
+			NewLV(0, "mainnet-beta", StakeActivating, voteAccount),
+			NewLV(0, "mainnet-beta", StakeDeactivating, voteAccount),
+		),
+		watcher.StakeAccounts.makeCollectionTest(NewLV(2, "mainnet-beta", voteAccount)),
+		watcher.Delegators.makeCollectionTest(NewLV(1, "mainnet-beta", voteAccount)),
+		watcher.EpochStakeChange.makeCollectionTest(NewLV(15*rpc.LamportsInSol, "mainnet-beta", voteAccount)),
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := testutil.CollectAndCompare(watcher, strings.NewReader(test.ExpectedResponse), test.Name)
+			assert.NoError(t, err)
+		})
+	}
+	assert.Equal(t, 1, testutil.CollectAndCount(watcher, "solana_validator_large_delegation_changes_total"))
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultProcPath is where running processes are looked up for --discover-local
+const DefaultProcPath = "/proc"
+
+var (
+	validatorBinaries = []string{"agave-validator", "solana-validator"}
+
+	// genesisHashNetworks maps the well-known genesis hashes to their network
+	genesisHashNetworks = map[string]string{
+		"5eykt4UsFv8P8NJdTREpY1vzqKqZKvdpKuc147dw2N9d": "mainnet-beta",
+		"4uhcVJyU9pJkvQyS88uRDiswHXSCkY3zQawwpjk2NsNY": "testnet",
+		"EtWTRABZaYq6iMfeYKouRu166VU2xqa1wcaWoxPkrZBG": "devnet",
+	}
+)
+
+// LocalNode is what could be derived from the command line of a co-located validator process.
+// The identity is deliberately not taken from --identity, as that is a keypair file. Duplicate
+// identity detection still needs --identities, and then also checks the node's own identity
+// through getIdentity on the discovered RPC port.
+type LocalNode struct {
+	Pid                 int
+	Binary              string
+	RpcUrl              string
+	LedgerPath          string
+	AccountsPath        string
+	SnapshotsPath       string
+	ExpectedGenesisHash string
+	Entrypoints         []string
+	NetworkName         string
+}
+
+// DiscoverLocalNode finds a running agave-validator or solana-validator process under procRoot
+func DiscoverLocalNode(procRoot string) (*LocalNode, error) {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		// processes may exit or be unreadable (other users); skip them
+		cmdline, err := os.ReadFile(filepath.Join(procRoot, entry.Name(), "cmdline"))
+		if err != nil || len(cmdline) == 0 {
+			continue
+		}
+		args := strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00")
+		if !isValidatorBinary(args[0]) {
+			continue
+		}
+
+		node := parseValidatorArgs(args[1:])
+		node.Pid = pid
+		node.Binary = filepath.Base(args[0])
+		return node, nil
+	}
+	return nil, errors.New("no running agave-validator or solana-validator process found")
+}
+
+func isValidatorBinary(argv0 string) bool {
+	name := filepath.Base(argv0)
+	for _, binary := range validatorBinaries {
+		if name == binary {
+			return true
+		}
+	}
+	return false
+}
+
+// parseValidatorArgs extracts the exporter-relevant settings from validator arguments,
+// accepting both "--flag value" and "--flag=value" forms.
+func parseValidatorArgs(args []string) *LocalNode {
+	node := &LocalNode{}
+	var rpcPort, rpcBindAddress string
+
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if !strings.HasPrefix(name, "-") {
+			continue
+		}
+		if !hasValue {
+			// only consume the next argument if it is not itself a flag
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				value = args[i+1]
+				i++
+			} else {
+				continue
+			}
+		}
+
+		switch name {
+		case "--rpc-port":
+			rpcPort = value
+		case "--rpc-bind-address":
+			rpcBindAddress = value
+		case "--ledger", "-l":
+			node.LedgerPath = value
+		case "--accounts":
+			// may be repeated; the first path is as good as any for disk monitoring
+			if node.AccountsPath == "" {
+				node.AccountsPath = value
+			}
+		case "--snapshots", "--full-snapshot-archive-path":
+			node.SnapshotsPath = value
+		case "--expected-genesis-hash":
+			node.ExpectedGenesisHash = value
+		case "--entrypoint", "-n":
+			node.Entrypoints = append(node.Entrypoints, value)
+		}
+	}
+
+	if rpcPort != "" {
+		host := "127.0.0.1"
+		if rpcBindAddress != "" && rpcBindAddress != "0.0.0.0" && rpcBindAddress != "::" {
+			host = rpcBindAddress
+		}
+		node.RpcUrl = "http://" + net.JoinHostPort(host, rpcPort)
+	}
+	node.NetworkName = inferNetwork(node.ExpectedGenesisHash, node.Entrypoints)
+	return node
+}
+
+// inferNetwork prefers the genesis hash, which is unambiguous, over entrypoint hostnames
+func inferNetwork(genesisHash string, entrypoints []string) string {
+	if network, ok := genesisHashNetworks[genesisHash]; ok {
+		return network
+	}
+	for _, entrypoint := range entrypoints {
+		host := entrypoint
+		if h, _, err := net.SplitHostPort(entrypoint); err == nil {
+			host = h
+		}
+		for _, network := range []string{"mainnet-beta", "testnet", "devnet"} {
+			if strings.HasSuffix(host, "."+network+".solana.com") {
+				return network
+			}
+		}
+	}
+	return ""
+}
+
+// String lists the discovered settings for logging
+func (n *LocalNode) String() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s (pid %d)", n.Binary, n.Pid)
+	for _, field := range [][2]string{
+		{"rpcUrl", n.RpcUrl},
+		{"ledger", n.LedgerPath},
+		{"accounts", n.AccountsPath},
+		{"snapshots", n.SnapshotsPath},
+		{"expectedGenesisHash", n.ExpectedGenesisHash},
+		{"entrypoints", strings.Join(n.Entrypoints, ",")},
+		{"network", n.NetworkName},
+	} {
+		if field[1] != "" {
+			fmt.Fprintf(&builder, " %s=%s", field[0], field[1])
+		}
+	}
+	return builder.String()
+}
+
+// flagDefaults maps exporter flag names to the discovered values
+func (n *LocalNode) flagDefaults() map[string]string {
+	return map[string]string{
+		"rpc-url":        n.RpcUrl,
+		"network":        n.NetworkName,
+		"ledger-path":    n.LedgerPath,
+		"accounts-path":  n.AccountsPath,
+		"snapshots-path": n.SnapshotsPath,
+	}
+}
+
+// applyDiscoveredDefaults sets the discovered values on flags that were not given explicitly,
+// so the command line always wins over discovery.
+func applyDiscoveredDefaults(flagSet *flag.FlagSet, node *LocalNode) error {
+	explicit := make(map[string]bool)
+	flagSet.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	for name, value := range node.flagDefaults() {
+		if value == "" || explicit[name] || flagSet.Lookup(name) == nil {
+			continue
+		}
+		if err := flagSet.Set(name, value); err != nil {
+			return fmt.Errorf("failed to apply discovered %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeFakeProcess adds a process with the given arguments to a fake /proc tree
+func writeFakeProcess(t *testing.T, procRoot, pid string, args ...string) {
+	dir := filepath.Join(procRoot, pid)
+	assert.NoError(t, os.MkdirAll(dir, 0o755))
+	cmdline := ""
+	if len(args) > 0 {
+		cmdline = strings.Join(args, "\x00") + "\x00"
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "cmdline"), []byte(cmdline), 0o644))
+}
+
+func TestDiscoverLocalNode(t *testing.T) {
+	procRoot := t.TempDir()
+	writeFakeProcess(t, procRoot, "1", "/sbin/init")
+	writeFakeProcess(t, procRoot, "2") // kernel threads have an empty cmdline
+	writeFakeProcess(t, procRoot, "self", "/usr/bin/agave-validator", "--rpc-port", "1")
+	writeFakeProcess(t, procRoot, "4242",
+		"/home/sol/.local/share/solana/install/active_release/bin/agave-validator",
+		"--identity", "/home/sol/validator-keypair.json",
+		"--ledger", "/mnt/ledger",
+		"--accounts", "/mnt/accounts",
+		"--rpc-port", "8899",
+		"--expected-genesis-hash", "4uhcVJyU9pJkvQyS88uRDiswHXSCkY3zQawwpjk2NsNY",
+		"--entrypoint", "entrypoint.testnet.solana.com:8001",
+		"--entrypoint", "entrypoint2.testnet.solana.com:8001",
+	)
+
+	node, err := DiscoverLocalNode(procRoot)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 4242, node.Pid)
+	assert.Equal(t, "agave-validator", node.Binary)
+	assert.Equal(t, "http://127.0.0.1:8899", node.RpcUrl)
+	assert.Equal(t, "/mnt/ledger", node.LedgerPath)
+	assert.Equal(t, "/mnt/accounts", node.AccountsPath)
+	assert.Equal(t, "4uhcVJyU9pJkvQyS88uRDiswHXSCkY3zQawwpjk2NsNY", node.ExpectedGenesisHash)
+	assert.Equal(t, []string{"entrypoint.testnet.solana.com:8001", "entrypoint2.testnet.solana.com:8001"}, node.Entrypoints)
+	assert.Equal(t, "testnet", node.NetworkName)
+
+	t.Run("no validator", func(t *testing.T) {
+		emptyRoot := t.TempDir()
+		writeFakeProcess(t, emptyRoot, "1", "/sbin/init")
+		_, err := DiscoverLocalNode(emptyRoot)
+		assert.Error(t, err)
+	})
+}
+
+func TestParseValidatorArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected LocalNode
+	}{
+		{
+			name: "equals form and short flags",
+			args: []string{
+				"-l", "/data/ledger",
+				"--rpc-port=9000",
+				"--rpc-bind-address=10.0.0.5",
+				"--accounts=/nvme1/accounts",
+				"--accounts=/nvme2/accounts",
+				"--snapshots", "/data/snapshots",
+				"-n", "entrypoint.mainnet-beta.solana.com:8001",
+			},
+			expected: LocalNode{
+				RpcUrl:        "http://10.0.0.5:9000",
+				LedgerPath:    "/data/ledger",
+				AccountsPath:  "/nvme1/accounts",
+				SnapshotsPath: "/data/snapshots",
+				Entrypoints:   []string{"entrypoint.mainnet-beta.solana.com:8001"},
+				NetworkName:   "mainnet-beta",
+			},
+		},
+		{
+			name: "wildcard bind address and valueless flags",
+			args: []string{
+				"--no-voting",
+				"--rpc-bind-address", "0.0.0.0",
+				"--full-rpc-api",
+				"--rpc-port", "8899",
+				"--expected-genesis-hash", "EtWTRABZaYq6iMfeYKouRu166VU2xqa1wcaWoxPkrZBG",
+				"--entrypoint", "entrypoint.testnet.solana.com:8001",
+			},
+			expected: LocalNode{
+				RpcUrl:              "http://127.0.0.1:8899",
+				ExpectedGenesisHash: "EtWTRABZaYq6iMfeYKouRu166VU2xqa1wcaWoxPkrZBG",
+				Entrypoints:         []string{"entrypoint.testnet.solana.com:8001"},
+				// the genesis hash is trusted over entrypoint hostnames
+				NetworkName: "devnet",
+			},
+		},
+		{
+			name: "private cluster",
+			args: []string{
+				"--full-snapshot-archive-path", "/snapshots",
+				"--entrypoint", "10.1.2.3:8001",
+			},
+			expected: LocalNode{
+				SnapshotsPath: "/snapshots",
+				Entrypoints:   []string{"10.1.2.3:8001"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, &tt.expected, parseValidatorArgs(tt.args))
+		})
+	}
+}
+
+func TestApplyDiscoveredDefaults(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	rpcUrl := flagSet.String("rpc-url", "http://localhost:8899", "")
+	networkName := flagSet.String("network", "mainnet-beta", "")
+	ledgerPath := flagSet.String("ledger-path", "", "")
+	accountsPath := flagSet.String("accounts-path", "", "")
+	snapshotsPath := flagSet.String("snapshots-path", "", "")
+	assert.NoError(t, flagSet.Parse([]string{"-ledger-path", "/override/ledger"}))
+
+	node := &LocalNode{
+		RpcUrl:       "http://127.0.0.1:9000",
+		NetworkName:  "testnet",
+		LedgerPath:   "/mnt/ledger",
+		AccountsPath: "/mnt/accounts",
+	}
+	assert.NoError(t, applyDiscoveredDefaults(flagSet, node))
+
+	assert.Equal(t, "http://127.0.0.1:9000", *rpcUrl)
+	assert.Equal(t, "testnet", *networkName)
+	assert.Equal(t, "/override/ledger", *ledgerPath)
+	assert.Equal(t, "/mnt/accounts", *accountsPath)
+	assert.Empty(t, *snapshotsPath)
+}
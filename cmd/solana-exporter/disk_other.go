@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || dragonfly)
+
+package main
+
+import "errors"
+
+// statFilesystem is only implemented where syscall.Statfs is; Solana nodes do not run elsewhere
+func statFilesystem(path string) (*FilesystemStats, error) {
+	return nil, errors.New("filesystem stats are not supported on this platform")
+}
@@ -0,0 +1,17 @@
+//go:build linux || darwin || freebsd || dragonfly
+
+package main
+
+import "syscall"
+
+// statFilesystem returns the capacity of the filesystem holding path
+func statFilesystem(path string) (*FilesystemStats, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return nil, err
+	}
+	return &FilesystemStats{
+		TotalBytes: uint64(stat.Blocks) * uint64(stat.Bsize),
+		FreeBytes:  uint64(stat.Bavail) * uint64(stat.Bsize),
+	}, nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/naviat/solana-rpc-exporter/pkg/slog"
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	CountryLabel  = "country"
+	ASNLabel      = "asn"
+	ProviderLabel = "provider"
+	UnknownValue  = "unknown"
+)
+
+type (
+	// GeoRecord is the subset of MaxMind Country/City and ASN records the exporter uses
+	GeoRecord struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+		ASN          uint   `maxminddb:"autonomous_system_number"`
+		Organization string `maxminddb:"autonomous_system_organization"`
+	}
+
+	geoLookup interface {
+		Lookup(ip net.IP) (GeoRecord, error)
+	}
+
+	// mmdbLookup merges lookups across databases, so the separate MaxMind
+	// Country and ASN files can be used together.
+	mmdbLookup struct {
+		readers []*maxminddb.Reader
+	}
+
+	geoKey struct {
+		country  string
+		asn      string
+		provider string
+	}
+
+	GeoCollector struct {
+		rpcClient *rpc.Client
+		logger    *zap.SugaredLogger
+		config    *ExporterConfig
+		lookup    geoLookup
+
+		NodesByCountry *GaugeDesc
+		NodesByASN     *GaugeDesc
+		StakeByCountry *GaugeDesc
+		StakeByASN     *GaugeDesc
+	}
+)
+
+func openGeoDatabases(paths []string) (*mmdbLookup, error) {
+	lookup := &mmdbLookup{}
+	for _, path := range paths {
+		reader, err := maxminddb.Open(path)
+		if err != nil {
+			lookup.Close()
+			return nil, fmt.Errorf("failed to open GeoIP database %s: %w", path, err)
+		}
+		lookup.readers = append(lookup.readers, reader)
+	}
+	return lookup, nil
+}
+
+func (l *mmdbLookup) Lookup(ip net.IP) (GeoRecord, error) {
+	var merged GeoRecord
+	for _, reader := range l.readers {
+		var record GeoRecord
+		if err := reader.Lookup(ip, &record); err != nil {
+			return merged, err
+		}
+		if merged.Country.ISOCode == "" {
+			merged.Country.ISOCode = record.Country.ISOCode
+		}
+		if merged.ASN == 0 {
+			merged.ASN = record.ASN
+			merged.Organization = record.Organization
+		}
+	}
+	return merged, nil
+}
+
+func (l *mmdbLookup) Close() {
+	for _, reader := range l.readers {
+		_ = reader.Close()
+	}
+}
+
+func NewGeoCollector(client *rpc.Client, config *ExporterConfig, lookup geoLookup) *GeoCollector {
+	return &GeoCollector{
+		rpcClient: client,
+		logger:    slog.Get(),
+		config:    config,
+		lookup:    lookup,
+
+		NodesByCountry: NewGaugeDesc(
+			"solana_cluster_nodes_by_country",
+			"Number of gossip nodes per country of their gossip IP",
+			NetworkLabel, CountryLabel,
+		),
+		NodesByASN: NewGaugeDesc(
+			"solana_cluster_nodes_by_asn",
+			"Number of gossip nodes per autonomous system (hosting provider) of their gossip IP",
+			NetworkLabel, ASNLabel, ProviderLabel,
+		),
+		StakeByCountry: NewGaugeDesc(
+			"solana_cluster_stake_by_country",
+			"Activated stake (in SOL) of validators per country of their gossip IP",
+			NetworkLabel, CountryLabel,
+		),
+		StakeByASN: NewGaugeDesc(
+			"solana_cluster_stake_by_asn",
+			"Activated stake (in SOL) of validators per autonomous system (hosting provider) of their gossip IP",
+			NetworkLabel, ASNLabel, ProviderLabel,
+		),
+	}
+}
+
+func (c *GeoCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.NodesByCountry.Desc
+	ch <- c.NodesByASN.Desc
+	ch <- c.StakeByCountry.Desc
+	ch <- c.StakeByASN.Desc
+}
+
+func (c *GeoCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx := rpc.WithPriority(context.Background(), rpc.PriorityBulk)
+
+	nodes, err := c.rpcClient.GetClusterNodes(ctx)
+	if err != nil {
+		c.logger.Errorw("Failed to get cluster nodes, skipping node geography", "error", err)
+		return
+	}
+
+	locations := make(map[string]geoKey, len(nodes))
+	for _, node := range nodes {
+		locations[node.Pubkey] = c.locate(node)
+	}
+
+	nodesByCountry := make(map[string]int)
+	nodesByASN := make(map[geoKey]int)
+	for _, key := range locations {
+		nodesByCountry[key.country]++
+		nodesByASN[geoKey{asn: key.asn, provider: key.provider}]++
+	}
+	for country, count := range nodesByCountry {
+		ch <- c.NodesByCountry.MustNewConstMetric(float64(count), c.config.NetworkName, country)
+	}
+	for key, count := range nodesByASN {
+		ch <- c.NodesByASN.MustNewConstMetric(float64(count), c.config.NetworkName, key.asn, key.provider)
+	}
+
+	voteAccounts, err := c.rpcClient.GetVoteAccounts(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		c.logger.Warnw("Failed to get vote accounts, skipping stake distribution", "error", err)
+		return
+	}
+
+	stakeByCountry := make(map[string]int64)
+	stakeByASN := make(map[geoKey]int64)
+	for _, accounts := range [][]rpc.VoteAccount{voteAccounts.Current, voteAccounts.Delinquent} {
+		for _, account := range accounts {
+			key, ok := locations[account.NodePubkey]
+			if !ok {
+				key = geoKey{country: UnknownValue, asn: UnknownValue, provider: UnknownValue}
+			}
+			stakeByCountry[key.country] += account.ActivatedStake
+			stakeByASN[geoKey{asn: key.asn, provider: key.provider}] += account.ActivatedStake
+		}
+	}
+	for country, stake := range stakeByCountry {
+		ch <- c.StakeByCountry.MustNewConstMetric(float64(stake)/rpc.LamportsInSol, c.config.NetworkName, country)
+	}
+	for key, stake := range stakeByASN {
+		ch <- c.StakeByASN.MustNewConstMetric(float64(stake)/rpc.LamportsInSol, c.config.NetworkName, key.asn, key.provider)
+	}
+}
+
+// locate resolves a node's gossip IP, falling back to "unknown" for every field that cannot be resolved
+func (c *GeoCollector) locate(node rpc.ClusterNode) geoKey {
+	key := geoKey{country: UnknownValue, asn: UnknownValue, provider: UnknownValue}
+	if node.Gossip == nil {
+		return key
+	}
+	host, _, err := net.SplitHostPort(*node.Gossip)
+	if err != nil {
+		return key
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return key
+	}
+
+	record, err := c.lookup.Lookup(ip)
+	if err != nil {
+		c.logger.Debugw("GeoIP lookup failed", "ip", host, "error", err)
+		return key
+	}
+	if record.Country.ISOCode != "" {
+		key.country = record.Country.ISOCode
+	}
+	if record.ASN != 0 {
+		key.asn = strconv.FormatUint(uint64(record.ASN), 10)
+	}
+	if record.Organization != "" {
+		key.provider = record.Organization
+	}
+	return key
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type staticGeoLookup map[string]GeoRecord
+
+func (l staticGeoLookup) Lookup(ip net.IP) (GeoRecord, error) {
+	record, ok := l[ip.String()]
+	if !ok {
+		return GeoRecord{}, errors.New("not found")
+	}
+	return record, nil
+}
+
+func newGeoRecord(country string, asn uint, organization string) GeoRecord {
+	var record GeoRecord
+	record.Country.ISOCode = country
+	record.ASN = asn
+	record.Organization = organization
+	return record
+}
+
+func TestGeoCollector(t *testing.T) {
+	_, client := rpc.NewMockClient(t, map[string]any{
+		"getClusterNodes": []map[string]any{
+			{"pubkey": "nodeA", "gossip": "10.0.0.1:8001"},
+			{"pubkey": "nodeB", "gossip": "10.0.0.2:8001"},
+			{"pubkey": "nodeC", "gossip": "10.0.0.3:8001"},
+			{"pubkey": "nodeD", "gossip": nil},
+		},
+		"getVoteAccounts": map[string]any{
+			"current": []map[string]any{
+				{"votePubkey": "voteA", "nodePubkey": "nodeA", "activatedStake": 3 * rpc.LamportsInSol},
+				{"votePubkey": "voteB", "nodePubkey": "nodeB", "activatedStake": 2 * rpc.LamportsInSol},
+			},
+			"delinquent": []map[string]any{
+				{"votePubkey": "voteC", "nodePubkey": "nodeC", "activatedStake": 1 * rpc.LamportsInSol},
+			},
+		},
+	})
+	lookup := staticGeoLookup{
+		"10.0.0.1": newGeoRecord("DE", 24940, "Hetzner Online GmbH"),
+		"10.0.0.2": newGeoRecord("DE", 24940, "Hetzner Online GmbH"),
+		"10.0.0.3": newGeoRecord("US", 0, ""),
+	}
+	collector := NewGeoCollector(client, &ExporterConfig{NetworkName: "mainnet-beta"}, lookup)
+
+	tests := []collectionTest{
+		collector.NodesByCountry.makeCollectionTest(
+			NewLV(2, "DE", "mainnet-beta"),
+			NewLV(1, "US", "mainnet-beta"),
+			NewLV(1, "unknown", "mainnet-beta"),
+		),
+		collector.NodesByASN.makeCollectionTest(
+			NewLV(2, "24940", "mainnet-beta", "Hetzner Online GmbH"),
+			NewLV(2, "unknown", "mainnet-beta", "unknown"),
+		),
+		collector.StakeByCountry.makeCollectionTest(
+			NewLV(5, "DE", "mainnet-beta"),
+			NewLV(1, "US", "mainnet-beta"),
+		),
+		collector.StakeByASN.makeCollectionTest(
+			NewLV(5, "24940", "mainnet-beta", "Hetzner Online GmbH"),
+			NewLV(1, "unknown", "mainnet-beta", "unknown"),
+		),
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := testutil.CollectAndCompare(collector, strings.NewReader(test.ExpectedResponse), test.Name)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestGeoCollector_ClusterNodesUnavailable(t *testing.T) {
+	_, client := rpc.NewMockClient(t, map[string]any{
+		"getClusterNodes": &rpc.RPCError{Code: -32603, Message: "Internal error"},
+	})
+	collector := NewGeoCollector(client, &ExporterConfig{NetworkName: "mainnet-beta"}, staticGeoLookup{})
+
+	// the failure is logged and the series skipped, so the rest of the scrape still succeeds
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(collector)
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+	assert.Empty(t, families)
+}
+
+func TestOpenGeoDatabases_Missing(t *testing.T) {
+	_, err := openGeoDatabases([]string{"/nonexistent/GeoLite2-Country.mmdb"})
+	assert.Error(t, err)
+}
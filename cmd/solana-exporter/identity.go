@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/naviat/solana-rpc-exporter/pkg/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	IdentityLabel = "identity"
+
+	// IdentityPollInterval is how often gossip is checked for our identities
+	IdentityPollInterval = 30 * time.Second
+	// ContactFlapWindow is the window in which repeated gossip contact changes count as flapping
+	ContactFlapWindow = 10 * time.Minute
+)
+
+type (
+	identityState struct {
+		gossip         string
+		entries        int
+		contactChanges int
+		recentChanges  []time.Time
+		duplicate      bool
+	}
+
+	// IdentityWatcher looks for signs that one of our identity keypairs is running on
+	// more than one machine. Two nodes sharing an identity fight over the same gossip
+	// entry, which shows up either as several entries for the pubkey or as its
+	// advertised contact address flipping back and forth between polls.
+	IdentityWatcher struct {
+		client *rpc.Client
+		logger *zap.SugaredLogger
+		config *ExporterConfig
+
+		mu         sync.RWMutex
+		identities map[string]*identityState
+
+		IdentityDuplicate      *GaugeDesc
+		IdentityGossipEntries  *GaugeDesc
+		IdentityContactChanges *GaugeDesc
+	}
+)
+
+func NewIdentityWatcher(client *rpc.Client, config *ExporterConfig) *IdentityWatcher {
+	watcher := &IdentityWatcher{
+		client:     client,
+		logger:     slog.Get(),
+		config:     config,
+		identities: make(map[string]*identityState),
+
+		IdentityDuplicate: NewGaugeDesc(
+			"solana_identity_duplicate",
+			"Whether the identity appears to be running on more than one node (1 = duplicate suspected)",
+			NetworkLabel, IdentityLabel,
+		),
+		IdentityGossipEntries: NewGaugeDesc(
+			"solana_identity_gossip_entries",
+			"Number of getClusterNodes entries advertising the identity",
+			NetworkLabel, IdentityLabel,
+		),
+		IdentityContactChanges: NewGaugeDesc(
+			"solana_identity_gossip_contact_changes",
+			"Number of times the identity's gossip contact address changed since the exporter started",
+			NetworkLabel, IdentityLabel,
+		),
+	}
+	for _, identity := range config.Identities {
+		watcher.identities[identity] = &identityState{}
+	}
+	return watcher
+}
+
+func (w *IdentityWatcher) WatchIdentities(ctx context.Context) error {
+	ticker := time.NewTicker(IdentityPollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.checkIdentities(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *IdentityWatcher) checkIdentities(ctx context.Context) {
+	// the RPC node's own identity is always watched, even if not configured
+	identity, err := w.client.GetIdentity(ctx)
+	if err != nil {
+		w.logger.Errorw("Failed to get node identity", "error", err)
+	} else {
+		w.mu.Lock()
+		if _, ok := w.identities[identity]; !ok {
+			w.identities[identity] = &identityState{}
+		}
+		w.mu.Unlock()
+	}
+
+	nodes, err := w.client.GetClusterNodes(rpc.WithPriority(ctx, rpc.PriorityBulk))
+	if err != nil {
+		w.logger.Errorw("Failed to get cluster nodes for identity check", "error", err)
+		return
+	}
+	w.update(nodes, time.Now())
+}
+
+// update applies one getClusterNodes snapshot to the tracked identities
+func (w *IdentityWatcher) update(nodes []rpc.ClusterNode, now time.Time) {
+	gossipByIdentity := make(map[string][]string)
+	for _, node := range nodes {
+		gossip := ""
+		if node.Gossip != nil {
+			gossip = *node.Gossip
+		}
+		gossipByIdentity[node.Pubkey] = append(gossipByIdentity[node.Pubkey], gossip)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for identity, state := range w.identities {
+		addresses := gossipByIdentity[identity]
+		state.entries = len(addresses)
+
+		if len(addresses) == 1 {
+			if state.gossip != "" && addresses[0] != state.gossip {
+				state.contactChanges++
+				state.recentChanges = append(state.recentChanges, now)
+				w.logger.Warnw("Gossip contact address changed",
+					"identity", identity,
+					"previous", state.gossip,
+					"current", addresses[0],
+				)
+			}
+			state.gossip = addresses[0]
+		}
+
+		recent := state.recentChanges[:0]
+		for _, changed := range state.recentChanges {
+			if now.Sub(changed) <= ContactFlapWindow {
+				recent = append(recent, changed)
+			}
+		}
+		state.recentChanges = recent
+
+		// a single change is a node migration; a change back within the window is flapping
+		duplicate := state.entries > 1 || len(state.recentChanges) >= 2
+		if duplicate && !state.duplicate {
+			sort.Strings(addresses)
+			w.logger.Errorw("Duplicate identity suspected",
+				"identity", identity,
+				"gossip_entries", state.entries,
+				"gossip_addresses", addresses,
+				"recent_contact_changes", len(state.recentChanges),
+			)
+		} else if !duplicate && state.duplicate {
+			w.logger.Infow("Duplicate identity no longer suspected", "identity", identity)
+		}
+		state.duplicate = duplicate
+	}
+}
+
+func (w *IdentityWatcher) Describe(ch chan<- *prometheus.Desc) {
+	ch <- w.IdentityDuplicate.Desc
+	ch <- w.IdentityGossipEntries.Desc
+	ch <- w.IdentityContactChanges.Desc
+}
+
+func (w *IdentityWatcher) Collect(ch chan<- prometheus.Metric) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	for identity, state := range w.identities {
+		duplicate := 0.0
+		if state.duplicate {
+			duplicate = 1
+		}
+		ch <- w.IdentityDuplicate.MustNewConstMetric(duplicate, w.config.NetworkName, identity)
+		ch <- w.IdentityGossipEntries.MustNewConstMetric(float64(state.entries), w.config.NetworkName, identity)
+		ch <- w.IdentityContactChanges.MustNewConstMetric(float64(state.contactChanges), w.config.NetworkName, identity)
+	}
+}
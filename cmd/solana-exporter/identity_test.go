@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func clusterNode(pubkey string, gossip string) rpc.ClusterNode {
+	return rpc.ClusterNode{Pubkey: pubkey, Gossip: &gossip}
+}
+
+func TestIdentityWatcher_Update(t *testing.T) {
+	watcher := NewIdentityWatcher(nil, &ExporterConfig{NetworkName: "mainnet-beta", Identities: []string{"nodeA", "nodeB"}})
+	start := time.Now()
+
+	watcher.update([]rpc.ClusterNode{clusterNode("nodeA", "10.0.0.1:8001"), clusterNode("nodeB", "10.0.0.5:8001")}, start)
+	assert.False(t, watcher.identities["nodeA"].duplicate)
+
+	// a single move is a migration, not a duplicate
+	watcher.update([]rpc.ClusterNode{clusterNode("nodeA", "10.0.0.2:8001"), clusterNode("nodeB", "10.0.0.5:8001")}, start.Add(time.Minute))
+	assert.False(t, watcher.identities["nodeA"].duplicate)
+
+	// flipping back within the window is two machines fighting over the identity
+	watcher.update([]rpc.ClusterNode{clusterNode("nodeA", "10.0.0.1:8001"), clusterNode("nodeB", "10.0.0.5:8001")}, start.Add(2*time.Minute))
+	assert.True(t, watcher.identities["nodeA"].duplicate)
+	assert.Equal(t, 2, watcher.identities["nodeA"].contactChanges)
+
+	// once the changes age out of the window the signal clears
+	watcher.update([]rpc.ClusterNode{clusterNode("nodeA", "10.0.0.1:8001"), clusterNode("nodeB", "10.0.0.5:8001")}, start.Add(time.Hour))
+	assert.False(t, watcher.identities["nodeA"].duplicate)
+
+	// several gossip entries for the same pubkey
+	watcher.update([]rpc.ClusterNode{
+		clusterNode("nodeA", "10.0.0.1:8001"),
+		clusterNode("nodeB", "10.0.0.5:8001"),
+		clusterNode("nodeB", "10.0.0.6:8001"),
+	}, start.Add(time.Hour))
+	assert.True(t, watcher.identities["nodeB"].duplicate)
+
+	tests := []collectionTest{
+		watcher.IdentityDuplicate.makeCollectionTest(
+			NewLV(0, "nodeA", "mainnet-beta"),
+			NewLV(1, "nodeB", "mainnet-beta"),
+		),
+		watcher.IdentityGossipEntries.makeCollectionTest(
+			NewLV(1, "nodeA", "mainnet-beta"),
+			NewLV(2, "nodeB", "mainnet-beta"),
+		),
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := testutil.CollectAndCompare(watcher, strings.NewReader(test.ExpectedResponse), test.Name)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestIdentityWatcher_CheckIdentities(t *testing.T) {
+	_, client := rpc.NewMockClient(t, map[string]any{
+		"getIdentity": map[string]string{"identity": "nodeA"},
+		"getClusterNodes": []map[string]any{
+			{"pubkey": "nodeA", "gossip": "10.0.0.1:8001"},
+			{"pubkey": "nodeA", "gossip": "10.0.0.2:8001"},
+		},
+	})
+	watcher := NewIdentityWatcher(client, &ExporterConfig{NetworkName: "mainnet-beta"})
+
+	watcher.checkIdentities(context.Background())
+	if assert.Contains(t, watcher.identities, "nodeA") {
+		assert.True(t, watcher.identities["nodeA"].duplicate)
+		assert.Equal(t, 2, watcher.identities["nodeA"].entries)
+	}
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	SnapshotTypeLabel  = "type"
+	DirectoryLabel     = "directory"
+	SnapshotFull       = "full"
+	SnapshotIncrement  = "incremental"
+	DirectoryLedger    = "ledger"
+	DirectoryAccounts  = "accounts"
+	DirectorySnapshots = "snapshots"
+
+	// LedgerInspectInterval is how often snapshots and free space are checked
+	LedgerInspectInterval = time.Minute
+	// DiskUsageInterval is how often directory trees are walked; the accounts
+	// directory alone can hold hundreds of thousands of files.
+	DiskUsageInterval = 10 * time.Minute
+	// DiskGrowthWindow is the history used to project when a filesystem fills up
+	DiskGrowthWindow = 6 * time.Hour
+	// minDiskGrowthHistory is the least history needed before projecting
+	minDiskGrowthHistory = 10 * time.Minute
+)
+
+var (
+	fullSnapshotPattern        = regexp.MustCompile(`^snapshot-(\d+)-([1-9A-HJ-NP-Za-km-z]+)\.tar(\.zst|\.bz2|\.gz|\.lz4)?$`)
+	incrementalSnapshotPattern = regexp.MustCompile(`^incremental-snapshot-(\d+)-(\d+)-([1-9A-HJ-NP-Za-km-z]+)\.tar(\.zst|\.bz2|\.gz|\.lz4)?$`)
+)
+
+type (
+	// SnapshotArchive is a snapshot archive identified from its filename
+	SnapshotArchive struct {
+		Type     string
+		BaseSlot int64 // only set for incremental snapshots
+		Slot     int64
+		Hash     string
+		Path     string
+		Size     int64
+	}
+
+	// FilesystemStats is the capacity of the filesystem holding a directory
+	FilesystemStats struct {
+		TotalBytes uint64
+		FreeBytes  uint64
+	}
+
+	diskSample struct {
+		timestamp time.Time
+		usedBytes uint64
+	}
+
+	directoryState struct {
+		path       string
+		usageBytes int64
+		usageKnown bool
+		filesystem *FilesystemStats
+		samples    []diskSample
+	}
+
+	// LedgerInspector inspects the ledger directory of a co-located node: snapshot
+	// archives, disk usage of the ledger, accounts and snapshot directories, and
+	// the free space left on their filesystems.
+	LedgerInspector struct {
+		logger *zap.SugaredLogger
+		config *ExporterConfig
+
+		mu          sync.RWMutex
+		snapshots   map[string]*SnapshotArchive
+		directories map[string]*directoryState
+		lastUsage   time.Time
+
+		SnapshotSlot    *GaugeDesc
+		DiskUsage       *GaugeDesc
+		DiskFree        *GaugeDesc
+		DiskTotal       *GaugeDesc
+		DaysUntilFull   *GaugeDesc
+		DiskGrowthBytes *GaugeDesc
+	}
+)
+
+// ParseSnapshotArchiveName parses full (snapshot-<slot>-<hash>.tar.zst) and incremental
+// (incremental-snapshot-<base>-<slot>-<hash>.tar.zst) snapshot archive filenames.
+func ParseSnapshotArchiveName(name string) (*SnapshotArchive, bool) {
+	if match := fullSnapshotPattern.FindStringSubmatch(name); match != nil {
+		slot, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		return &SnapshotArchive{Type: SnapshotFull, Slot: slot, Hash: match[2]}, true
+	}
+	if match := incrementalSnapshotPattern.FindStringSubmatch(name); match != nil {
+		baseSlot, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		slot, err := strconv.ParseInt(match[2], 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		return &SnapshotArchive{Type: SnapshotIncrement, BaseSlot: baseSlot, Slot: slot, Hash: match[3]}, true
+	}
+	return nil, false
+}
+
+// FindSnapshotArchives returns the newest full and incremental archive in dir, keyed by type
+func FindSnapshotArchives(dir string) (map[string]*SnapshotArchive, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := make(map[string]*SnapshotArchive)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		archive, ok := ParseSnapshotArchiveName(entry.Name())
+		if !ok {
+			continue
+		}
+		if current, exists := latest[archive.Type]; exists && current.Slot >= archive.Slot {
+			continue
+		}
+		archive.Path = filepath.Join(dir, entry.Name())
+		if info, err := entry.Info(); err == nil {
+			archive.Size = info.Size()
+		}
+		latest[archive.Type] = archive
+	}
+	return latest, nil
+}
+
+// directorySize sums the size of all regular files below path
+func directorySize(path string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// files come and go while the node runs (compaction, snapshot cleanup)
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total, err
+}
+
+func NewLedgerInspector(config *ExporterConfig) *LedgerInspector {
+	accountsPath := config.AccountsPath
+	if accountsPath == "" {
+		accountsPath = filepath.Join(config.LedgerPath, "accounts")
+	}
+
+	return &LedgerInspector{
+		logger:    slog.Get(),
+		config:    config,
+		snapshots: make(map[string]*SnapshotArchive),
+		directories: map[string]*directoryState{
+			DirectoryLedger:    {path: filepath.Join(config.LedgerPath, "rocksdb")},
+			DirectoryAccounts:  {path: accountsPath},
+			DirectorySnapshots: {path: config.snapshotArchivePath()},
+		},
+
+		SnapshotSlot: NewGaugeDesc(
+			"solana_local_snapshot_slot",
+			"Slot of the newest local snapshot archive, by type (full, incremental)",
+			NetworkLabel, SnapshotTypeLabel,
+		),
+		DiskUsage: NewGaugeDesc(
+			"solana_local_disk_usage_bytes",
+			"Bytes used by the ledger (rocksdb), accounts and snapshots directories",
+			NetworkLabel, DirectoryLabel,
+		),
+		DiskFree: NewGaugeDesc(
+			"solana_local_disk_free_bytes",
+			"Bytes available on the filesystem holding the directory",
+			NetworkLabel, DirectoryLabel,
+		),
+		DiskTotal: NewGaugeDesc(
+			"solana_local_disk_total_bytes",
+			"Size in bytes of the filesystem holding the directory",
+			NetworkLabel, DirectoryLabel,
+		),
+		DiskGrowthBytes: NewGaugeDesc(
+			"solana_local_disk_growth_bytes_per_day",
+			"Growth of used bytes on the filesystem holding the directory, over the last 6 hours",
+			NetworkLabel, DirectoryLabel,
+		),
+		DaysUntilFull: NewGaugeDesc(
+			"solana_local_disk_days_until_full",
+			"Projected days until the filesystem holding the directory is full at the current growth rate (+Inf if not growing)",
+			NetworkLabel, DirectoryLabel,
+		),
+	}
+}
+
+func (l *LedgerInspector) WatchLedger(ctx context.Context) error {
+	ticker := time.NewTicker(LedgerInspectInterval)
+	defer ticker.Stop()
+
+	for {
+		l.inspect(time.Now())
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *LedgerInspector) inspect(now time.Time) {
+	snapshots, err := FindSnapshotArchives(l.config.snapshotArchivePath())
+	if err != nil {
+		l.logger.Errorw("Failed to list snapshot archives", "path", l.config.snapshotArchivePath(), "error", err)
+	}
+
+	l.mu.RLock()
+	walkUsage := now.Sub(l.lastUsage) >= DiskUsageInterval
+	l.mu.RUnlock()
+
+	usage := make(map[string]int64)
+	filesystems := make(map[string]*FilesystemStats)
+	for name, directory := range l.directories {
+		stats, err := statFilesystem(directory.path)
+		if err != nil {
+			l.logger.Debugw("Failed to stat filesystem", "directory", name, "path", directory.path, "error", err)
+		} else {
+			filesystems[name] = stats
+		}
+
+		if !walkUsage {
+			continue
+		}
+		if name == DirectorySnapshots {
+			// the archive directory is usually the ledger root; only count the archives
+			// themselves plus the unpacked bank snapshots next to them
+			size := snapshotArchivesSize(directory.path)
+			if bankSnapshots, err := directorySize(filepath.Join(directory.path, "snapshot")); err == nil {
+				size += bankSnapshots
+			}
+			usage[name] = size
+			continue
+		}
+		size, err := directorySize(directory.path)
+		if err != nil {
+			l.logger.Errorw("Failed to measure directory size", "directory", name, "path", directory.path, "error", err)
+			continue
+		}
+		usage[name] = size
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if snapshots != nil {
+		l.snapshots = snapshots
+	}
+	if walkUsage {
+		l.lastUsage = now
+	}
+	for name, directory := range l.directories {
+		if size, ok := usage[name]; ok {
+			directory.usageBytes = size
+			directory.usageKnown = true
+		}
+		stats, ok := filesystems[name]
+		if !ok {
+			continue
+		}
+		directory.filesystem = stats
+		directory.samples = append(directory.samples, diskSample{timestamp: now, usedBytes: stats.TotalBytes - stats.FreeBytes})
+		for len(directory.samples) > 0 && now.Sub(directory.samples[0].timestamp) > DiskGrowthWindow {
+			directory.samples = directory.samples[1:]
+		}
+	}
+}
+
+// snapshotArchivesSize sums the size of every snapshot archive in dir, not only the newest
+func snapshotArchivesSize(dir string) int64 {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	var total int64
+	for _, entry := range entries {
+		if _, ok := ParseSnapshotArchiveName(entry.Name()); !ok || entry.IsDir() {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			total += info.Size()
+		}
+	}
+	return total
+}
+
+// growthPerSecond returns the growth of used bytes over the sample window, if there is enough history
+func (d *directoryState) growthPerSecond() (float64, bool) {
+	if len(d.samples) < 2 {
+		return 0, false
+	}
+	first, last := d.samples[0], d.samples[len(d.samples)-1]
+	elapsed := last.timestamp.Sub(first.timestamp)
+	if elapsed < minDiskGrowthHistory {
+		return 0, false
+	}
+	return (float64(last.usedBytes) - float64(first.usedBytes)) / elapsed.Seconds(), true
+}
+
+// LatestSnapshot returns the newest local snapshot archive of the given type, if any
+func (l *LedgerInspector) LatestSnapshot(snapshotType string) *SnapshotArchive {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.snapshots[snapshotType]
+}
+
+func (l *LedgerInspector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- l.SnapshotSlot.Desc
+	ch <- l.DiskUsage.Desc
+	ch <- l.DiskFree.Desc
+	ch <- l.DiskTotal.Desc
+	ch <- l.DiskGrowthBytes.Desc
+	ch <- l.DaysUntilFull.Desc
+}
+
+func (l *LedgerInspector) Collect(ch chan<- prometheus.Metric) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for snapshotType, archive := range l.snapshots {
+		ch <- l.SnapshotSlot.MustNewConstMetric(float64(archive.Slot), l.config.NetworkName, snapshotType)
+	}
+
+	for name, directory := range l.directories {
+		if directory.usageKnown {
+			ch <- l.DiskUsage.MustNewConstMetric(float64(directory.usageBytes), l.config.NetworkName, name)
+		}
+		if directory.filesystem == nil {
+			continue
+		}
+		ch <- l.DiskFree.MustNewConstMetric(float64(directory.filesystem.FreeBytes), l.config.NetworkName, name)
+		ch <- l.DiskTotal.MustNewConstMetric(float64(directory.filesystem.TotalBytes), l.config.NetworkName, name)
+
+		growth, ok := directory.growthPerSecond()
+		if !ok {
+			continue
+		}
+		ch <- l.DiskGrowthBytes.MustNewConstMetric(growth*86400, l.config.NetworkName, name)
+		daysUntilFull := math.Inf(1)
+		if growth > 0 {
+			daysUntilFull = float64(directory.filesystem.FreeBytes) / growth / 86400
+		}
+		ch <- l.DaysUntilFull.MustNewConstMetric(daysUntilFull, l.config.NetworkName, name)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSnapshotArchiveName(t *testing.T) {
+	archive, ok := ParseSnapshotArchiveName("snapshot-297600000-8kmC3MyXmrPumLLTzpJDFmrnWoJtSWVr8ezmrVDqZj2K.tar.zst")
+	assert.True(t, ok)
+	assert.Equal(t, SnapshotFull, archive.Type)
+	assert.Equal(t, int64(297600000), archive.Slot)
+	assert.Equal(t, "8kmC3MyXmrPumLLTzpJDFmrnWoJtSWVr8ezmrVDqZj2K", archive.Hash)
+
+	archive, ok = ParseSnapshotArchiveName("incremental-snapshot-297600000-297609329-5ZbNjwWh6gGW2L8Ps6kSvUh3fUgJ5dxHgKSvCaE8XW7v.tar.zst")
+	assert.True(t, ok)
+	assert.Equal(t, SnapshotIncrement, archive.Type)
+	assert.Equal(t, int64(297600000), archive.BaseSlot)
+	assert.Equal(t, int64(297609329), archive.Slot)
+
+	for _, name := range []string{
+		"snapshot-297600000-8kmC3MyXmrPumLLTzpJDFmrnWoJtSWVr8ezmrVDqZj2K.tar.zst.tmp",
+		"snapshot-abc-8kmC3MyXmrPumLLTzpJDFmrnWoJtSWVr8ezmrVDqZj2K.tar.zst",
+		"genesis.tar.bz2",
+		"rocksdb",
+	} {
+		_, ok = ParseSnapshotArchiveName(name)
+		assert.False(t, ok, name)
+	}
+}
+
+func writeTestFile(t *testing.T, path string, size int) {
+	t.Helper()
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	assert.NoError(t, os.WriteFile(path, make([]byte, size), 0o644))
+}
+
+func TestLedgerInspector(t *testing.T) {
+	ledger := t.TempDir()
+	writeTestFile(t, filepath.Join(ledger, "snapshot-100-Hash1.tar.zst"), 100)
+	writeTestFile(t, filepath.Join(ledger, "snapshot-200-Hash2.tar.zst"), 200)
+	writeTestFile(t, filepath.Join(ledger, "incremental-snapshot-200-250-Hash3.tar.zst"), 10)
+	writeTestFile(t, filepath.Join(ledger, "snapshot", "250", "250"), 30)
+	writeTestFile(t, filepath.Join(ledger, "rocksdb", "000001.sst"), 1000)
+	writeTestFile(t, filepath.Join(ledger, "accounts", "run", "250.1"), 500)
+	writeTestFile(t, filepath.Join(ledger, "genesis.bin"), 5000)
+
+	inspector := NewLedgerInspector(&ExporterConfig{NetworkName: "mainnet-beta", LedgerPath: ledger})
+	now := time.Now()
+	inspector.inspect(now)
+
+	full := inspector.LatestSnapshot(SnapshotFull)
+	if assert.NotNil(t, full) {
+		assert.Equal(t, int64(200), full.Slot)
+		assert.Equal(t, "Hash2", full.Hash)
+		assert.Equal(t, int64(200), full.Size)
+	}
+	incremental := inspector.LatestSnapshot(SnapshotIncrement)
+	if assert.NotNil(t, incremental) {
+		assert.Equal(t, int64(250), incremental.Slot)
+	}
+
+	assert.Equal(t, int64(1000), inspector.directories[DirectoryLedger].usageBytes)
+	assert.Equal(t, int64(500), inspector.directories[DirectoryAccounts].usageBytes)
+	assert.Equal(t, int64(340), inspector.directories[DirectorySnapshots].usageBytes)
+	assert.NotNil(t, inspector.directories[DirectoryLedger].filesystem)
+
+	// not enough history for a projection yet
+	_, ok := inspector.directories[DirectoryLedger].growthPerSecond()
+	assert.False(t, ok)
+
+	inspector.inspect(now.Add(time.Hour))
+	assert.Len(t, inspector.directories[DirectoryLedger].samples, 2)
+	_, ok = inspector.directories[DirectoryLedger].growthPerSecond()
+	assert.True(t, ok)
+
+	assert.Equal(t, 2, testutil.CollectAndCount(inspector, "solana_local_snapshot_slot"))
+	assert.Equal(t, 3, testutil.CollectAndCount(inspector, "solana_local_disk_usage_bytes"))
+	assert.Equal(t, 3, testutil.CollectAndCount(inspector, "solana_local_disk_days_until_full"))
+}
+
+func TestDirectoryState_GrowthPerSecond(t *testing.T) {
+	now := time.Now()
+	state := &directoryState{
+		filesystem: &FilesystemStats{TotalBytes: 1000 * 86400, FreeBytes: 10 * 86400},
+		samples: []diskSample{
+			{timestamp: now.Add(-time.Hour), usedBytes: 0},
+			{timestamp: now, usedBytes: 3600},
+		},
+	}
+	growth, ok := state.growthPerSecond()
+	assert.True(t, ok)
+	assert.Equal(t, 1.0, growth)
+
+	// shrinking filesystems never fill up
+	state.samples[1].usedBytes = 0
+	growth, _ = state.growthPerSecond()
+	assert.Equal(t, 0.0, growth)
+}
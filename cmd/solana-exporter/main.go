@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
@@ -31,6 +32,23 @@ func main() {
 		cancel()
 	}()
 
+	// Subcommands bypass the exporter entirely
+	if len(os.Args) > 1 {
+		var subcommand func(context.Context, []string, io.Writer) error
+		switch os.Args[1] {
+		case "query":
+			subcommand = runQuery
+		case "report":
+			subcommand = runReport
+		}
+		if subcommand != nil {
+			if err := subcommand(ctx, os.Args[2:], os.Stdout); err != nil {
+				logger.Fatal(err)
+			}
+			return
+		}
+	}
+
 	// Load configuration
 	config, err := NewExporterConfigFromCLI(ctx)
 	if err != nil {
@@ -44,6 +62,11 @@ func main() {
 
 	// Initialize RPC client
 	client := rpc.NewRPCClient(config.RpcUrl, config.HttpTimeout)
+	traceMetrics := NewRPCTraceMetrics()
+	client.Observer = traceMetrics
+	client.Scheduler = rpc.NewScheduler(config.RpcMaxConcurrency, rpc.DefaultClassLimits)
+	schedulerMetrics := NewSchedulerMetrics(client.Scheduler)
+	client.Scheduler.Observer = schedulerMetrics
 
 	// Initialize collectors
 	collector := NewSolanaCollector(client, config)
@@ -67,10 +90,181 @@ func main() {
 	if err := prometheus.Register(collector); err != nil {
 		logger.Warnf("Failed to register collector: %v, continuing anyway", err)
 	}
+	if len(config.Identities) > 0 {
+		identityWatcher := NewIdentityWatcher(client, config)
+		go func() {
+			if err := identityWatcher.WatchIdentities(ctx); err != nil && err != context.Canceled {
+				logger.Errorf("Identity watcher stopped: %v", err)
+			}
+		}()
+		if err := prometheus.Register(identityWatcher); err != nil {
+			logger.Warnf("Failed to register identity watcher: %v, continuing anyway", err)
+		}
+	}
+	var ledgerInspector *LedgerInspector
+	if config.LedgerPath != "" {
+		ledgerInspector = NewLedgerInspector(config)
+		go func() {
+			if err := ledgerInspector.WatchLedger(ctx); err != nil && err != context.Canceled {
+				logger.Errorf("Ledger inspector stopped: %v", err)
+			}
+		}()
+		if err := prometheus.Register(ledgerInspector); err != nil {
+			logger.Warnf("Failed to register ledger inspector: %v, continuing anyway", err)
+		}
+	}
+	var restartGuard *RestartGuard
+	if config.SafeToRestart {
+		restartGuard = NewRestartGuard(client, config, ledgerInspector)
+		if err := prometheus.Register(restartGuard); err != nil {
+			logger.Warnf("Failed to register restart guard: %v, continuing anyway", err)
+		}
+	}
+	if config.BlockLatency {
+		// a dedicated client, so waiting in the shared scheduler queue is not counted as latency
+		blockLatencyWatcher := NewBlockLatencyWatcher(rpc.NewRPCClient(config.RpcUrl, config.HttpTimeout), config)
+		go func() {
+			if err := blockLatencyWatcher.WatchBlocks(ctx); err != nil && err != context.Canceled {
+				logger.Errorf("Block latency watcher stopped: %v", err)
+			}
+		}()
+		if err := prometheus.Register(blockLatencyWatcher); err != nil {
+			logger.Warnf("Failed to register block latency watcher: %v, continuing anyway", err)
+		}
+	}
+	if config.RollbackDetection {
+		rollbackDetector := NewRollbackDetector(client, config)
+		go func() {
+			if err := rollbackDetector.WatchRollbacks(ctx); err != nil && err != context.Canceled {
+				logger.Errorf("Rollback detector stopped: %v", err)
+			}
+		}()
+		if err := prometheus.Register(rollbackDetector); err != nil {
+			logger.Warnf("Failed to register rollback detector: %v, continuing anyway", err)
+		}
+	}
+	if len(config.SquadsMultisigs) > 0 {
+		squadsWatcher := NewSquadsWatcher(client, config)
+		go func() {
+			if err := squadsWatcher.WatchMultisigs(ctx); err != nil && err != context.Canceled {
+				logger.Errorf("Squads watcher stopped: %v", err)
+			}
+		}()
+		if err := prometheus.Register(squadsWatcher); err != nil {
+			logger.Warnf("Failed to register Squads watcher: %v, continuing anyway", err)
+		}
+	}
+	if len(config.ApyStakeAccounts) > 0 {
+		stakingApyWatcher := NewStakingApyWatcher(client, config)
+		go func() {
+			if err := stakingApyWatcher.WatchStakingApy(ctx); err != nil && err != context.Canceled {
+				logger.Errorf("Staking APY watcher stopped: %v", err)
+			}
+		}()
+		if err := prometheus.Register(stakingApyWatcher); err != nil {
+			logger.Warnf("Failed to register staking APY watcher: %v, continuing anyway", err)
+		}
+	}
+	if len(config.DelegationVoteAccounts) > 0 {
+		delegationWatcher := NewDelegationWatcher(client, config)
+		go func() {
+			if err := delegationWatcher.WatchDelegations(ctx); err != nil && err != context.Canceled {
+				logger.Errorf("Delegation watcher stopped: %v", err)
+			}
+		}()
+		if err := prometheus.Register(delegationWatcher); err != nil {
+			logger.Warnf("Failed to register delegation watcher: %v, continuing anyway", err)
+		}
+	}
+	if len(config.TokenMints) > 0 {
+		tokenFlowWatcher := NewTokenFlowWatcher(client, config)
+		go func() {
+			if err := tokenFlowWatcher.WatchTokenFlows(ctx); err != nil && err != context.Canceled {
+				logger.Errorf("Token flow watcher stopped: %v", err)
+			}
+		}()
+		if err := prometheus.Register(tokenFlowWatcher); err != nil {
+			logger.Warnf("Failed to register token flow watcher: %v, continuing anyway", err)
+		}
+	}
+	var priorityFeeAPI *PriorityFeeAPI
+	if config.PriorityFeeAPI {
+		priorityFeeAPI = NewPriorityFeeAPI(client, config)
+		if err := prometheus.Register(priorityFeeAPI); err != nil {
+			logger.Warnf("Failed to register priority fee API: %v, continuing anyway", err)
+		}
+	}
+	if config.AirdropProbe.Address != "" {
+		airdropProber := NewAirdropProber(client, config)
+		go func() {
+			if err := airdropProber.WatchAirdrops(ctx); err != nil && err != context.Canceled {
+				logger.Errorf("Airdrop prober stopped: %v", err)
+			}
+		}()
+		if err := prometheus.Register(airdropProber); err != nil {
+			logger.Warnf("Failed to register airdrop prober: %v, continuing anyway", err)
+		}
+	}
+	if len(config.AuditEndpoints) > 0 {
+		auditWatcher := NewSecurityAuditWatcher(config)
+		go func() {
+			if err := auditWatcher.WatchEndpoints(ctx); err != nil && err != context.Canceled {
+				logger.Errorf("Security audit watcher stopped: %v", err)
+			}
+		}()
+		if err := prometheus.Register(auditWatcher); err != nil {
+			logger.Warnf("Failed to register security audit watcher: %v, continuing anyway", err)
+		}
+	}
+	if config.NativeHealth {
+		if err := prometheus.Register(NewNativeHealthCollector(client, config)); err != nil {
+			logger.Warnf("Failed to register native health collector: %v, continuing anyway", err)
+		}
+	}
+	if config.VersionPolicy != "" {
+		policy, err := LoadVersionPolicy(config.VersionPolicy)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		if err := prometheus.Register(NewVersionPolicyCollector(client, config, policy)); err != nil {
+			logger.Warnf("Failed to register version policy collector: %v, continuing anyway", err)
+		}
+	}
+	if len(config.GeoIPDatabases) > 0 {
+		geoLookup, err := openGeoDatabases(config.GeoIPDatabases)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		defer geoLookup.Close()
+		if err := prometheus.Register(NewGeoCollector(client, config, geoLookup)); err != nil {
+			logger.Warnf("Failed to register geo collector: %v, continuing anyway", err)
+		}
+	}
+	if err := prometheus.Register(traceMetrics); err != nil {
+		logger.Warnf("Failed to register RPC trace metrics: %v, continuing anyway", err)
+	}
+	if err := prometheus.Register(schedulerMetrics); err != nil {
+		logger.Warnf("Failed to register RPC scheduler metrics: %v, continuing anyway", err)
+	}
+
+	var gatherer prometheus.Gatherer = prometheus.DefaultGatherer
+	if config.RecordingRules != "" {
+		rules, err := LoadRecordingRules(config.RecordingRules)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		gatherer = NewRuleEvaluator(gatherer, rules)
+	}
 
 	// Set up HTTP server
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/metrics", newMetricsHandler(prometheus.DefaultRegisterer, gatherer))
+	if restartGuard != nil {
+		mux.Handle("/api/v1/safe-to-restart", restartGuard)
+	}
+	if priorityFeeAPI != nil {
+		mux.Handle("/api/v1/priority-fee", priorityFeeAPI)
+	}
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		_, err := client.GetHealth(r.Context())
 		if err != nil {
@@ -109,3 +303,12 @@ func main() {
 
 	logger.Info("Exporter stopped")
 }
+
+// newMetricsHandler serves the gathered metrics, negotiating the OpenMetrics format
+// when the scraper asks for it: exemplars are only exposed in that format.
+func newMetricsHandler(registerer prometheus.Registerer, gatherer prometheus.Gatherer) http.Handler {
+	return promhttp.InstrumentMetricHandler(
+		registerer,
+		promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+	)
+}
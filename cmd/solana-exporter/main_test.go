@@ -2,6 +2,11 @@ package main
 
 import (
 	"github.com/naviat/solana-rpc-exporter/pkg/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 )
@@ -11,3 +16,32 @@ func TestMain(m *testing.M) {
 	code := m.Run()
 	os.Exit(code)
 }
+
+func TestNewMetricsHandler_Exemplars(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "test_block_fee_lamports",
+		Help:    "Test histogram",
+		Buckets: []float64{5000, 10000},
+	})
+	registry.MustRegister(histogram)
+	histogram.(prometheus.ExemplarObserver).ObserveWithExemplar(7500, prometheus.Labels{"slot": "297609329"})
+
+	server := httptest.NewServer(newMetricsHandler(registry, registry))
+	defer server.Close()
+
+	fetch := func(accept string) string {
+		req, err := http.NewRequest("GET", server.URL, nil)
+		assert.NoError(t, err)
+		req.Header.Set("Accept", accept)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return string(body)
+	}
+
+	assert.Contains(t, fetch("application/openmetrics-text; version=1.0.0"), `# {slot="297609329"} 7500`)
+	assert.NotContains(t, fetch("text/plain"), `slot="297609329"`)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/naviat/solana-rpc-exporter/pkg/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// NativeHealthError is the status of the native health endpoint when it could not be read
+const NativeHealthError = "error"
+
+// NativeHealthCollector probes the node's native GET /health endpoint next to the getHealth
+// method on every scrape. Load balancers usually route on the former, so a disagreement between
+// the two is how traffic ends up on a node the exporter reports unhealthy, or the reverse.
+type NativeHealthCollector struct {
+	client *rpc.Client
+	logger *zap.SugaredLogger
+	config *ExporterConfig
+
+	NativeHealth       *GaugeDesc
+	NativeHealthStatus *GaugeDesc
+	Disagreement       *GaugeDesc
+}
+
+func NewNativeHealthCollector(client *rpc.Client, config *ExporterConfig) *NativeHealthCollector {
+	return &NativeHealthCollector{
+		client: client,
+		logger: slog.Get(),
+		config: config,
+
+		NativeHealth: NewGaugeDesc(
+			"solana_node_native_health",
+			"1 if the RPC node's GET /health endpoint answers ok, 0 otherwise",
+			NetworkLabel,
+		),
+		NativeHealthStatus: NewGaugeDesc(
+			"solana_node_native_health_status",
+			fmt.Sprintf(
+				"Always 1, with the answer of the RPC node's GET /health endpoint as %s (%s, %s, %s or %s)",
+				StatusLabel, rpc.HealthOk, rpc.HealthBehind, rpc.HealthUnknown, NativeHealthError,
+			),
+			NetworkLabel, StatusLabel,
+		),
+		Disagreement: NewGaugeDesc(
+			"solana_node_health_disagreement",
+			"1 if GET /health and the getHealth method disagree on whether the RPC node is healthy, "+
+				"0 if they agree; missing if either could not be read",
+			NetworkLabel,
+		),
+	}
+}
+
+func (c *NativeHealthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.NativeHealth.Desc
+	ch <- c.NativeHealthStatus.Desc
+	ch <- c.Disagreement.Desc
+}
+
+func (c *NativeHealthCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx := rpc.WithPriority(context.Background(), rpc.PriorityCritical)
+
+	native, nativeErr := c.client.GetNativeHealth(ctx)
+	status, nativeHealthy := NativeHealthError, 0.0
+	if nativeErr != nil {
+		c.logger.Errorw("Failed to get native health", "error", nativeErr)
+	} else {
+		status = native.Status
+		if native.IsHealthy() {
+			nativeHealthy = 1
+		}
+	}
+	ch <- c.NativeHealth.MustNewConstMetric(nativeHealthy, c.config.NetworkName)
+	ch <- c.NativeHealthStatus.MustNewConstMetric(1, c.config.NetworkName, status)
+
+	// called directly, as GetHealth caches healthy answers and would hide a node that just fell behind
+	_, err := c.client.Call(ctx, "getHealth", nil)
+	if err != nil && !rpc.IsNodeUnhealthy(err) {
+		c.logger.Errorw("Failed to get health for comparison", "error", err)
+		return
+	}
+	if nativeErr != nil {
+		return
+	}
+
+	disagreement := 0.0
+	if methodHealthy := err == nil; methodHealthy != native.IsHealthy() {
+		disagreement = 1
+		c.logger.Warnw("Native and JSON-RPC health disagree", "native", status, "getHealthOk", methodHealthy)
+	}
+	ch <- c.Disagreement.MustNewConstMetric(disagreement, c.config.NetworkName)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNativeHealthCollector(t *testing.T) {
+	server, client := rpc.NewMockClient(t, map[string]any{"getHealth": "ok"})
+	collector := NewNativeHealthCollector(client, &ExporterConfig{NetworkName: "mainnet-beta"})
+	unhealthy := &rpc.RPCError{Code: rpc.NodeUnhealthyCode, Message: "Node is behind by 42 slots"}
+
+	tests := []struct {
+		name         string
+		native       string
+		method       any
+		healthy      float64
+		status       string
+		disagreement float64
+	}{
+		{"both healthy", "ok", "ok", 1, rpc.HealthOk, 0},
+		// a load balancer would take the node out while the exporter reports it healthy
+		{"native behind", "behind", "ok", 0, rpc.HealthBehind, 1},
+		{"both unhealthy", "unknown", unhealthy, 0, rpc.HealthUnknown, 0},
+		{"method behind", "ok", unhealthy, 1, rpc.HealthOk, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server.SetOpt(rpc.NativeHealthOpt, nil, test.native)
+			server.SetOpt(rpc.EasyResultsOpt, "getHealth", test.method)
+			for _, collection := range []collectionTest{
+				collector.NativeHealth.makeCollectionTest(NewLV(test.healthy, "mainnet-beta")),
+				collector.NativeHealthStatus.makeCollectionTest(NewLV(1, "mainnet-beta", test.status)),
+				collector.Disagreement.makeCollectionTest(NewLV(test.disagreement, "mainnet-beta")),
+			} {
+				err := testutil.CollectAndCompare(collector, strings.NewReader(collection.ExpectedResponse), collection.Name)
+				assert.NoError(t, err, collection.Name)
+			}
+		})
+	}
+
+	// without an answer from /health there is nothing to compare
+	server.SetOpt(rpc.NativeHealthOpt, nil, "Bad Gateway")
+	server.SetOpt(rpc.EasyResultsOpt, "getHealth", "ok")
+	err := testutil.CollectAndCompare(
+		collector,
+		strings.NewReader(collector.NativeHealthStatus.makeCollectionTest(NewLV(1, "mainnet-beta", NativeHealthError)).ExpectedResponse),
+		"solana_node_native_health_status",
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, testutil.CollectAndCount(collector, "solana_node_health_disagreement"))
+}
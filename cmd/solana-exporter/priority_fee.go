@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/naviat/solana-rpc-exporter/pkg/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	// PriorityFeeCacheTTL is how long the fee samples of an account set are reused, a few slots
+	PriorityFeeCacheTTL = 2 * time.Second
+	// DefaultPriorityFeePercentile is used when the request does not ask for one
+	DefaultPriorityFeePercentile = 50.0
+	// maxPriorityFeeEntries bounds the number of distinct account sets cached
+	maxPriorityFeeEntries = 256
+	// maxPriorityFeeAccounts is the most accounts getRecentPrioritizationFees accepts
+	maxPriorityFeeAccounts = 128
+
+	PriorityFeeHit     = "hit"
+	PriorityFeeMiss    = "miss"
+	PriorityFeeInvalid = "invalid"
+	PriorityFeeError   = "error"
+)
+
+type (
+	// PriorityFeeLimits bound the recommended price, in micro-lamports per compute unit
+	PriorityFeeLimits struct {
+		Floor int64
+		// Ceiling of zero leaves the recommendation unbounded
+		Ceiling int64
+	}
+
+	// PriorityFeeRecommendation is the answer served by /api/v1/priority-fee
+	PriorityFeeRecommendation struct {
+		MicroLamports int64    `json:"microLamports"`
+		Percentile    float64  `json:"percentile"`
+		Accounts      []string `json:"accounts"`
+		// Raw is the percentile of the samples before the floor and ceiling were applied
+		Raw        int64     `json:"raw"`
+		Floor      int64     `json:"floor"`
+		Ceiling    int64     `json:"ceiling,omitempty"`
+		Samples    int       `json:"samples"`
+		NewestSlot int64     `json:"newestSlot"`
+		FetchedAt  time.Time `json:"fetchedAt"`
+	}
+
+	// priorityFeeSamples are the recent fees of one account set, sorted ascending
+	priorityFeeSamples struct {
+		fees       []int64
+		newestSlot int64
+		fetchedAt  time.Time
+		lastUsed   time.Time
+	}
+
+	// priorityFeeFetch is a getRecentPrioritizationFees call shared by concurrent cache misses
+	priorityFeeFetch struct {
+		done  chan struct{}
+		entry *priorityFeeSamples
+		err   error
+	}
+
+	// PriorityFeeAPI recommends a priority fee from the node's recent prioritization fees, so
+	// transaction senders share one cached view instead of each polling the node.
+	PriorityFeeAPI struct {
+		client *rpc.Client
+		logger *zap.SugaredLogger
+		config *ExporterConfig
+
+		mu       sync.Mutex
+		entries  map[string]*priorityFeeSamples
+		inFlight map[string]*priorityFeeFetch
+
+		Requests     *prometheus.CounterVec
+		CacheEntries *GaugeDesc
+	}
+)
+
+func NewPriorityFeeAPI(client *rpc.Client, config *ExporterConfig) *PriorityFeeAPI {
+	return &PriorityFeeAPI{
+		client:   client,
+		logger:   slog.Get(),
+		config:   config,
+		entries:  make(map[string]*priorityFeeSamples),
+		inFlight: make(map[string]*priorityFeeFetch),
+
+		Requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "solana_priority_fee_requests_total",
+				Help: fmt.Sprintf(
+					"Priority fee recommendation requests, by %s (%s, %s, %s or %s)",
+					StatusLabel, PriorityFeeHit, PriorityFeeMiss, PriorityFeeInvalid, PriorityFeeError,
+				),
+			},
+			[]string{NetworkLabel, StatusLabel},
+		),
+		CacheEntries: NewGaugeDesc(
+			"solana_priority_fee_cache_entries",
+			"Number of distinct account sets whose fee samples are cached",
+			NetworkLabel,
+		),
+	}
+}
+
+// parsePriorityFeeQuery reads the account set, deduplicated and sorted, and the percentile
+func parsePriorityFeeQuery(query url.Values) ([]string, float64, error) {
+	seen := make(map[string]bool)
+	var accounts []string
+	for _, value := range query["accounts"] {
+		for _, account := range splitList(value) {
+			if !seen[account] {
+				seen[account] = true
+				accounts = append(accounts, account)
+			}
+		}
+	}
+	if len(accounts) > maxPriorityFeeAccounts {
+		return nil, 0, fmt.Errorf("at most %d accounts are supported, got %d", maxPriorityFeeAccounts, len(accounts))
+	}
+	sort.Strings(accounts)
+
+	percentile := DefaultPriorityFeePercentile
+	if value := query.Get("percentile"); value != "" {
+		var err error
+		percentile, err = strconv.ParseFloat(value, 64)
+		if err != nil || percentile < 0 || percentile > 100 {
+			return nil, 0, fmt.Errorf("percentile must be a number between 0 and 100, got %q", value)
+		}
+	}
+	return accounts, percentile, nil
+}
+
+// feePercentile returns the nearest-rank percentile of ascending fees
+func feePercentile(fees []int64, percentile float64) int64 {
+	if len(fees) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(percentile / 100 * float64(len(fees))))
+	return fees[min(max(rank-1, 0), len(fees)-1)]
+}
+
+// recommend computes the recommendation for samples, applying the configured limits
+func (a *PriorityFeeAPI) recommend(accounts []string, percentile float64, samples *priorityFeeSamples) *PriorityFeeRecommendation {
+	limits := a.config.PriorityFeeLimits
+	raw := feePercentile(samples.fees, percentile)
+	price := max(raw, limits.Floor)
+	if limits.Ceiling > 0 {
+		price = min(price, limits.Ceiling)
+	}
+	if accounts == nil {
+		accounts = []string{}
+	}
+	return &PriorityFeeRecommendation{
+		MicroLamports: price,
+		Percentile:    percentile,
+		Accounts:      accounts,
+		Raw:           raw,
+		Floor:         limits.Floor,
+		Ceiling:       limits.Ceiling,
+		Samples:       len(samples.fees),
+		NewestSlot:    samples.newestSlot,
+		FetchedAt:     samples.fetchedAt,
+	}
+}
+
+// samples returns the fee samples of an account set, from the cache if fresh, and whether they were
+// cached. Concurrent misses of the same account set wait for a single fetch, which counts as cached
+// for all but the caller that made it.
+func (a *PriorityFeeAPI) samples(ctx context.Context, accounts []string, now time.Time) (*priorityFeeSamples, bool, error) {
+	key := strings.Join(accounts, ",")
+
+	a.mu.Lock()
+	if entry, ok := a.entries[key]; ok && now.Sub(entry.fetchedAt) < PriorityFeeCacheTTL {
+		entry.lastUsed = now
+		a.mu.Unlock()
+		return entry, true, nil
+	}
+	if fetch, ok := a.inFlight[key]; ok {
+		a.mu.Unlock()
+		select {
+		case <-fetch.done:
+			return fetch.entry, true, fetch.err
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+	fetch := &priorityFeeFetch{done: make(chan struct{})}
+	a.inFlight[key] = fetch
+	a.mu.Unlock()
+
+	// the callers waiting on the fetch must not fail because this one went away
+	fetch.entry, fetch.err = a.fetchSamples(context.WithoutCancel(ctx), accounts, now)
+
+	a.mu.Lock()
+	delete(a.inFlight, key)
+	if fetch.err == nil {
+		if _, ok := a.entries[key]; !ok && len(a.entries) >= maxPriorityFeeEntries {
+			a.evictLeastRecentlyUsed()
+		}
+		a.entries[key] = fetch.entry
+	}
+	a.mu.Unlock()
+	close(fetch.done)
+	return fetch.entry, false, fetch.err
+}
+
+func (a *PriorityFeeAPI) fetchSamples(ctx context.Context, accounts []string, now time.Time) (*priorityFeeSamples, error) {
+	fees, err := a.client.GetRecentPrioritizationFees(ctx, accounts)
+	if err != nil {
+		return nil, err
+	}
+	entry := &priorityFeeSamples{fetchedAt: now, lastUsed: now}
+	for _, fee := range fees {
+		entry.fees = append(entry.fees, fee.PrioritizationFee)
+		entry.newestSlot = max(entry.newestSlot, fee.Slot)
+	}
+	sort.Slice(entry.fees, func(i, j int) bool { return entry.fees[i] < entry.fees[j] })
+	return entry, nil
+}
+
+func (a *PriorityFeeAPI) evictLeastRecentlyUsed() {
+	var oldestKey string
+	var oldest time.Time
+	for key, entry := range a.entries {
+		if oldest.IsZero() || entry.lastUsed.Before(oldest) {
+			oldestKey, oldest = key, entry.lastUsed
+		}
+	}
+	delete(a.entries, oldestKey)
+}
+
+// ServeHTTP answers /api/v1/priority-fee?accounts=<a,b>&percentile=<p> with a recommendation as JSON
+func (a *PriorityFeeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	accounts, percentile, err := parsePriorityFeeQuery(r.URL.Query())
+	if err != nil {
+		a.Requests.WithLabelValues(a.config.NetworkName, PriorityFeeInvalid).Inc()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	samples, cached, err := a.samples(r.Context(), accounts, time.Now())
+	if err != nil {
+		a.Requests.WithLabelValues(a.config.NetworkName, PriorityFeeError).Inc()
+		a.logger.Errorw("Failed to get recent prioritization fees", "accounts", accounts, "error", err)
+		http.Error(w, "failed to get recent prioritization fees", http.StatusBadGateway)
+		return
+	}
+	status := PriorityFeeMiss
+	if cached {
+		status = PriorityFeeHit
+	}
+	a.Requests.WithLabelValues(a.config.NetworkName, status).Inc()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(a.recommend(accounts, percentile, samples)); err != nil {
+		a.logger.Errorw("Failed to write priority fee recommendation", "error", err)
+	}
+}
+
+func (a *PriorityFeeAPI) Describe(ch chan<- *prometheus.Desc) {
+	a.Requests.Describe(ch)
+	ch <- a.CacheEntries.Desc
+}
+
+func (a *PriorityFeeAPI) Collect(ch chan<- prometheus.Metric) {
+	a.Requests.Collect(ch)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ch <- a.CacheEntries.MustNewConstMetric(float64(len(a.entries)), a.config.NetworkName)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePriorityFeeQuery(t *testing.T) {
+	accounts, percentile, err := parsePriorityFeeQuery(url.Values{"accounts": {"b,a", "b"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, accounts)
+	assert.Equal(t, DefaultPriorityFeePercentile, percentile)
+
+	accounts, percentile, err = parsePriorityFeeQuery(url.Values{"percentile": {"90"}})
+	assert.NoError(t, err)
+	assert.Empty(t, accounts)
+	assert.Equal(t, 90.0, percentile)
+
+	for _, invalid := range []string{"-1", "101", "high"} {
+		_, _, err = parsePriorityFeeQuery(url.Values{"percentile": {invalid}})
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestFeePercentile(t *testing.T) {
+	fees := []int64{0, 0, 100, 200, 1_000, 5_000, 10_000, 10_000, 20_000, 50_000}
+	assert.Equal(t, int64(0), feePercentile(fees, 0))
+	assert.Equal(t, int64(1_000), feePercentile(fees, 50))
+	assert.Equal(t, int64(20_000), feePercentile(fees, 90))
+	assert.Equal(t, int64(50_000), feePercentile(fees, 100))
+	assert.Equal(t, int64(0), feePercentile(nil, 50))
+}
+
+func TestPriorityFeeAPI(t *testing.T) {
+	var fees []map[string]int64
+	for i := int64(0); i < 10; i++ {
+		fees = append(fees, map[string]int64{"slot": 1_000 + i, "prioritizationFee": i * 1_000})
+	}
+	server, client := rpc.NewMockClient(t, map[string]any{"getRecentPrioritizationFees": fees})
+	config := &ExporterConfig{NetworkName: "mainnet-beta", PriorityFeeLimits: PriorityFeeLimits{Floor: 500, Ceiling: 8_000}}
+	api := NewPriorityFeeAPI(client, config)
+
+	request := func(query string) (*httptest.ResponseRecorder, *PriorityFeeRecommendation) {
+		recorder := httptest.NewRecorder()
+		api.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/priority-fee?"+query, nil))
+		if recorder.Code != http.StatusOK {
+			return recorder, nil
+		}
+		var recommendation PriorityFeeRecommendation
+		assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&recommendation))
+		return recorder, &recommendation
+	}
+
+	_, recommendation := request("accounts=accountB,accountA&percentile=50")
+	if assert.NotNil(t, recommendation) {
+		assert.Equal(t, int64(4_000), recommendation.MicroLamports)
+		assert.Equal(t, []string{"accountA", "accountB"}, recommendation.Accounts)
+		assert.Equal(t, 10, recommendation.Samples)
+		assert.Equal(t, int64(1_009), recommendation.NewestSlot)
+	}
+
+	// clamped to the ceiling, and served from the same account set's cache
+	_, recommendation = request("accounts=accountA,accountB&percentile=100")
+	if assert.NotNil(t, recommendation) {
+		assert.Equal(t, int64(9_000), recommendation.Raw)
+		assert.Equal(t, int64(8_000), recommendation.MicroLamports)
+	}
+
+	// clamped to the floor
+	_, recommendation = request("percentile=0")
+	if assert.NotNil(t, recommendation) {
+		assert.Equal(t, int64(0), recommendation.Raw)
+		assert.Equal(t, int64(500), recommendation.MicroLamports)
+	}
+
+	recorder, _ := request("percentile=200")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	server.SetOpt(rpc.EasyResultsOpt, "getRecentPrioritizationFees", &rpc.RPCError{Code: -32603, Message: "Internal error"})
+	recorder, _ = request("accounts=accountC")
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+
+	for status, count := range map[string]float64{
+		PriorityFeeMiss:    2,
+		PriorityFeeHit:     1,
+		PriorityFeeInvalid: 1,
+		PriorityFeeError:   1,
+	} {
+		assert.Equal(t, count, testutil.ToFloat64(api.Requests.WithLabelValues("mainnet-beta", status)), status)
+	}
+	assert.Equal(t, 2, len(api.entries))
+}
+
+func TestPriorityFeeAPI_Cache(t *testing.T) {
+	_, client := rpc.NewMockClient(t, map[string]any{
+		"getRecentPrioritizationFees": []map[string]int64{{"slot": 1_000, "prioritizationFee": 10}},
+	})
+	api := NewPriorityFeeAPI(client, &ExporterConfig{NetworkName: "mainnet-beta"})
+	ctx := context.Background()
+	start := time.Now()
+
+	_, cached, err := api.samples(ctx, []string{"accountA"}, start)
+	assert.NoError(t, err)
+	assert.False(t, cached)
+	_, cached, _ = api.samples(ctx, []string{"accountA"}, start.Add(PriorityFeeCacheTTL/2))
+	assert.True(t, cached)
+	_, cached, _ = api.samples(ctx, []string{"accountA"}, start.Add(PriorityFeeCacheTTL))
+	assert.False(t, cached)
+
+	// the least recently used account set makes room for a new one
+	for i := 0; i < maxPriorityFeeEntries; i++ {
+		used := start.Add(PriorityFeeCacheTTL + time.Duration(i+1)*time.Second)
+		_, _, err = api.samples(ctx, []string{fmt.Sprintf("account%d", i)}, used)
+		assert.NoError(t, err)
+	}
+	assert.Len(t, api.entries, maxPriorityFeeEntries)
+	assert.NotContains(t, api.entries, "accountA")
+	assert.Contains(t, api.entries, "account0")
+}
+
+func TestPriorityFeeAPI_ConcurrentMisses(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":[{"slot":1000,"prioritizationFee":10}]}`))
+	}))
+	defer server.Close()
+	api := NewPriorityFeeAPI(rpc.NewRPCClient(server.URL, time.Second), &ExporterConfig{NetworkName: "mainnet-beta"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			samples, _, err := api.samples(context.Background(), []string{"accountA"}, time.Now())
+			assert.NoError(t, err)
+			assert.Equal(t, []int64{10}, samples.fees)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Empty(t, api.inFlight)
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+type QueryConfig struct {
+	RpcUrl      string
+	HttpTimeout time.Duration
+	Commitment  rpc.Commitment
+	Output      string
+	Method      string
+	Params      []any
+}
+
+// queryError is the printable form of an RPC error returned by a query
+type queryError struct {
+	Method  string `json:"method" yaml:"method"`
+	Code    int64  `json:"code" yaml:"code"`
+	Message string `json:"message" yaml:"message"`
+	Data    any    `json:"data,omitempty" yaml:"data,omitempty"`
+}
+
+// NewQueryConfigFromArgs parses the arguments of the query subcommand.
+// Flags may appear before, between or after the method and its params.
+func NewQueryConfigFromArgs(args []string) (*QueryConfig, error) {
+	var (
+		rpcUrl      string
+		httpTimeout int
+		commitment  string
+		output      string
+	)
+
+	flagSet := flag.NewFlagSet("query", flag.ContinueOnError)
+	flagSet.StringVar(&rpcUrl, "rpc-url", "http://localhost:8899", "Solana RPC URL (including protocol and path)")
+	flagSet.IntVar(&httpTimeout, "http-timeout", 60, "HTTP timeout in seconds for the RPC call")
+	flagSet.StringVar(&commitment, "commitment", "", "Commitment to add to the request config (finalized, confirmed, processed)")
+	flagSet.StringVar(&output, "output", OutputTable, "Output format (table, json, yaml)")
+
+	var positional []string
+	for {
+		if err := flagSet.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flagSet.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	if len(positional) == 0 {
+		return nil, errors.New("usage: solana-exporter query <method> [params...] [flags]")
+	}
+
+	switch output {
+	case OutputTable, OutputJSON, OutputYAML:
+	default:
+		return nil, fmt.Errorf("invalid output format %q, must be one of: table, json, yaml", output)
+	}
+
+	switch rpc.Commitment(commitment) {
+	case "", rpc.CommitmentFinalized, rpc.CommitmentConfirmed, rpc.CommitmentProcessed:
+	default:
+		return nil, fmt.Errorf("invalid commitment %q, must be one of: finalized, confirmed, processed", commitment)
+	}
+
+	params := make([]any, 0, len(positional)-1)
+	for _, arg := range positional[1:] {
+		params = append(params, parseQueryParam(arg))
+	}
+	if commitment != "" {
+		params = withCommitment(params, rpc.Commitment(commitment))
+	}
+
+	return &QueryConfig{
+		RpcUrl:      rpcUrl,
+		HttpTimeout: time.Duration(httpTimeout) * time.Second,
+		Commitment:  rpc.Commitment(commitment),
+		Output:      output,
+		Method:      positional[0],
+		Params:      params,
+	}, nil
+}
+
+// parseQueryParam decodes a param as JSON when possible, so numbers, booleans and
+// config objects keep their type; anything else (e.g. a pubkey) is sent as a string.
+func parseQueryParam(arg string) any {
+	if value, err := decodeJSON([]byte(arg)); err == nil {
+		return value
+	}
+	return arg
+}
+
+// withCommitment sets the commitment on a trailing config object, or appends one
+func withCommitment(params []any, commitment rpc.Commitment) []any {
+	if len(params) > 0 {
+		if config, ok := params[len(params)-1].(map[string]any); ok {
+			if _, exists := config["commitment"]; !exists {
+				config["commitment"] = string(commitment)
+			}
+			return params
+		}
+	}
+	return append(params, map[string]any{"commitment": string(commitment)})
+}
+
+// runQuery performs a single RPC call and writes the decoded result (or error) to out
+func runQuery(ctx context.Context, args []string, out io.Writer) error {
+	config, err := NewQueryConfigFromArgs(args)
+	if err != nil {
+		return err
+	}
+
+	client := rpc.NewRPCClient(config.RpcUrl, config.HttpTimeout)
+	result, err := client.Call(ctx, config.Method, config.Params)
+	if err != nil {
+		var rpcErr *rpc.RPCError
+		if !errors.As(err, &rpcErr) {
+			return err
+		}
+		data, decodeErr := rpc.DecodeErrorData(rpcErr)
+		if decodeErr != nil {
+			data = rpcErr.Data
+		}
+		printable := queryError{Method: rpcErr.Method, Code: rpcErr.Code, Message: rpcErr.Message, Data: data}
+		if writeErr := writeQueryOutput(out, config.Output, printable); writeErr != nil {
+			return writeErr
+		}
+		return err
+	}
+
+	decoded, err := decodeJSON(result)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", config.Method, err)
+	}
+	return writeQueryOutput(out, config.Output, decoded)
+}
+
+func writeQueryOutput(out io.Writer, format string, value any) error {
+	switch format {
+	case OutputJSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case OutputYAML:
+		encoder := yaml.NewEncoder(out)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(toPlainValue(value)); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "KEY\tVALUE")
+		for _, row := range flattenValue("", toPlainValue(value)) {
+			fmt.Fprintf(writer, "%s\t%s\n", row[0], row[1])
+		}
+		return writer.Flush()
+	}
+}
+
+// toPlainValue round-trips typed values through JSON so that the table and yaml
+// writers only ever see maps, slices and scalars, keyed by their JSON names.
+func toPlainValue(value any) any {
+	buffer, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	plain, err := decodeJSON(buffer)
+	if err != nil {
+		return value
+	}
+	return plain
+}
+
+// decodeJSON decodes into plain values while keeping integers exact;
+// lamport amounts and u64::MAX rent epochs do not survive a float64.
+func decodeJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return normalizeNumbers(value), nil
+}
+
+func normalizeNumbers(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalizeNumbers(item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeNumbers(item)
+		}
+		return v
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			return u
+		}
+		f, _ := v.Float64()
+		return f
+	default:
+		return value
+	}
+}
+
+// flattenValue turns nested results into sorted (path, value) rows for table output
+func flattenValue(prefix string, value any) [][2]string {
+	var rows [][2]string
+	switch v := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			rows = append(rows, flattenValue(joinPath(prefix, key), v[key])...)
+		}
+	case []any:
+		for i, item := range v {
+			rows = append(rows, flattenValue(fmt.Sprintf("%s[%d]", prefix, i), item)...)
+		}
+	case float64:
+		rows = append(rows, [2]string{pathOrResult(prefix), strconv.FormatFloat(v, 'f', -1, 64)})
+	case nil:
+		rows = append(rows, [2]string{pathOrResult(prefix), "null"})
+	default:
+		rows = append(rows, [2]string{pathOrResult(prefix), toString(v)})
+	}
+	return rows
+}
+
+func joinPath(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func pathOrResult(path string) string {
+	if path == "" {
+		return "result"
+	}
+	return path
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewQueryConfigFromArgs(t *testing.T) {
+	config, err := NewQueryConfigFromArgs([]string{
+		"getBlock", "297609329", "--rpc-url", "http://rpc:8899",
+		`{"maxSupportedTransactionVersion":0}`, "--commitment", "confirmed", "-output", "json",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "http://rpc:8899", config.RpcUrl)
+	assert.Equal(t, "getBlock", config.Method)
+	assert.Equal(t, OutputJSON, config.Output)
+	assert.Equal(t, []any{
+		int64(297609329),
+		map[string]any{"maxSupportedTransactionVersion": int64(0), "commitment": "confirmed"},
+	}, config.Params)
+
+	config, err = NewQueryConfigFromArgs([]string{"getBalance", "Vote111111111111111111111111111111111111111", "-commitment", "finalized"})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{
+		"Vote111111111111111111111111111111111111111",
+		map[string]any{"commitment": "finalized"},
+	}, config.Params)
+
+	_, err = NewQueryConfigFromArgs([]string{"-rpc-url", "http://rpc:8899"})
+	assert.Error(t, err)
+	_, err = NewQueryConfigFromArgs([]string{"getHealth", "-output", "xml"})
+	assert.Error(t, err)
+	_, err = NewQueryConfigFromArgs([]string{"getHealth", "-commitment", "rooted"})
+	assert.Error(t, err)
+}
+
+func TestRunQuery(t *testing.T) {
+	server, err := rpc.NewMockServer(map[string]any{
+		"getEpochInfo": map[string]int64{
+			"absoluteSlot": 166_598,
+			"epoch":        27,
+		},
+		"getHealth": &rpc.RPCError{
+			Code:    rpc.NodeUnhealthyCode,
+			Message: "Node is behind by 42 slots",
+			Data:    map[string]any{"numSlotsBehind": 42},
+		},
+	})
+	assert.NoError(t, err)
+	defer server.Close()
+
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	err = runQuery(ctx, []string{"getEpochInfo", "-rpc-url", server.URL()}, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "absoluteSlot  166598")
+	assert.Contains(t, out.String(), "epoch         27")
+
+	out.Reset()
+	err = runQuery(ctx, []string{"getEpochInfo", "-rpc-url", server.URL(), "-output", "yaml"}, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "absoluteSlot: 166598\n")
+
+	out.Reset()
+	err = runQuery(ctx, []string{"getHealth", "-rpc-url", server.URL(), "-output", "json"}, &out)
+	var rpcErr *rpc.RPCError
+	assert.True(t, errors.As(err, &rpcErr))
+	assert.Contains(t, out.String(), `"code": -32005`)
+	assert.Contains(t, out.String(), `"numSlotsBehind": 42`)
+}
@@ -0,0 +1,446 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatJSON     = "json"
+
+	// maxPointsPerQuery stays below Prometheus' 11,000 points per series limit
+	maxPointsPerQuery = 10_000
+)
+
+type (
+	ReportConfig struct {
+		PrometheusUrl string
+		Selector      string
+		From          time.Time
+		To            time.Time
+		Step          time.Duration
+		Format        string
+		HttpTimeout   time.Duration
+	}
+
+	promSample struct {
+		Timestamp time.Time
+		Value     float64
+	}
+
+	promRangeResponse struct {
+		Status    string `json:"status"`
+		ErrorType string `json:"errorType"`
+		Error     string `json:"error"`
+		Data      struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Metric map[string]string `json:"metric"`
+				Values [][2]any          `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+
+	Incident struct {
+		Start          time.Time `json:"start"`
+		End            time.Time `json:"end"`
+		Seconds        float64   `json:"duration_seconds"`
+		MaxSlotsBehind float64   `json:"max_slots_behind"`
+	}
+
+	EpochSummary struct {
+		Epoch                int64     `json:"epoch"`
+		Start                time.Time `json:"start"`
+		End                  time.Time `json:"end"`
+		Availability         float64   `json:"availability"`
+		TimeUnhealthySeconds float64   `json:"time_unhealthy_seconds"`
+		AvgSlotsBehind       float64   `json:"avg_slots_behind"`
+		MaxSlotsBehind       float64   `json:"max_slots_behind"`
+	}
+
+	// AvailabilityReport summarises node health over a period. Availability is the
+	// share of sampled time the node was healthy; time without samples (exporter or
+	// Prometheus down) is reported as NoDataSeconds rather than counted either way.
+	AvailabilityReport struct {
+		From                 time.Time      `json:"from"`
+		To                   time.Time      `json:"to"`
+		StepSeconds          float64        `json:"step_seconds"`
+		Selector             string         `json:"selector"`
+		Availability         float64        `json:"availability"`
+		TimeUnhealthySeconds float64        `json:"time_unhealthy_seconds"`
+		NoDataSeconds        float64        `json:"no_data_seconds"`
+		AvgSlotsBehind       float64        `json:"avg_slots_behind"`
+		MaxSlotsBehind       float64        `json:"max_slots_behind"`
+		Incidents            []Incident     `json:"incidents"`
+		Epochs               []EpochSummary `json:"epochs"`
+	}
+
+	// epochAccumulator collects the samples of one epoch while building a report
+	epochAccumulator struct {
+		summary   EpochSummary
+		samples   int
+		healthy   int
+		behindSum float64
+	}
+)
+
+func NewReportConfigFromArgs(args []string) (*ReportConfig, error) {
+	var (
+		prometheusUrl string
+		selector      string
+		from          string
+		to            string
+		step          int
+		format        string
+		httpTimeout   int
+	)
+
+	flagSet := flag.NewFlagSet("report", flag.ContinueOnError)
+	flagSet.StringVar(&prometheusUrl, "prometheus-url", "http://localhost:9090", "Base URL of the Prometheus HTTP API scraping the exporter")
+	flagSet.StringVar(&selector, "selector", "", `PromQL label matchers selecting the node, e.g. 'network="mainnet-beta",instance="rpc-1:8080"'`)
+	flagSet.StringVar(&from, "from", "", "Start of the report, RFC3339 or YYYY-MM-DD (default: 30 days before --to)")
+	flagSet.StringVar(&to, "to", "", "End of the report, RFC3339 or YYYY-MM-DD (default: now)")
+	flagSet.IntVar(&step, "step", 60, "Resolution of the report in seconds")
+	flagSet.StringVar(&format, "format", FormatMarkdown, "Output format (markdown, html, json)")
+	flagSet.IntVar(&httpTimeout, "http-timeout", 60, "HTTP timeout in seconds for Prometheus queries")
+	if err := flagSet.Parse(args); err != nil {
+		return nil, err
+	}
+
+	config := &ReportConfig{
+		PrometheusUrl: strings.TrimSuffix(prometheusUrl, "/"),
+		Selector:      selector,
+		To:            time.Now().UTC(),
+		Step:          time.Duration(step) * time.Second,
+		Format:        format,
+		HttpTimeout:   time.Duration(httpTimeout) * time.Second,
+	}
+
+	var err error
+	if to != "" {
+		if config.To, err = parseReportTime(to); err != nil {
+			return nil, fmt.Errorf("invalid --to: %w", err)
+		}
+	}
+	config.From = config.To.AddDate(0, 0, -30)
+	if from != "" {
+		if config.From, err = parseReportTime(from); err != nil {
+			return nil, fmt.Errorf("invalid --from: %w", err)
+		}
+	}
+
+	if !config.From.Before(config.To) {
+		return nil, errors.New("--from must be before --to")
+	}
+	if config.Step <= 0 {
+		return nil, errors.New("--step must be positive")
+	}
+	switch format {
+	case FormatMarkdown, FormatHTML, FormatJSON:
+	default:
+		return nil, fmt.Errorf("invalid format %q, must be one of: markdown, html, json", format)
+	}
+	return config, nil
+}
+
+func parseReportTime(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed.UTC(), nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// queryRange runs a range query, split into chunks that stay below the per-query point limit
+func (c *ReportConfig) queryRange(ctx context.Context, client *http.Client, query string) ([]promSample, error) {
+	var samples []promSample
+	chunk := c.Step * maxPointsPerQuery
+	for start := c.From; !start.After(c.To); start = start.Add(chunk + c.Step) {
+		end := start.Add(chunk)
+		if end.After(c.To) {
+			end = c.To
+		}
+
+		params := url.Values{}
+		params.Set("query", query)
+		params.Set("start", strconv.FormatInt(start.Unix(), 10))
+		params.Set("end", strconv.FormatInt(end.Unix(), 10))
+		params.Set("step", strconv.FormatFloat(c.Step.Seconds(), 'f', -1, 64))
+
+		req, err := http.NewRequestWithContext(ctx, "GET", c.PrometheusUrl+"/api/v1/query_range?"+params.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("prometheus query %q failed: %w", query, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading prometheus response: %w", err)
+		}
+
+		var response promRangeResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, fmt.Errorf("failed to decode prometheus response (HTTP %d): %w", resp.StatusCode, err)
+		}
+		if response.Status != "success" {
+			return nil, fmt.Errorf("prometheus query %q failed: %s: %s", query, response.ErrorType, response.Error)
+		}
+		if len(response.Data.Result) > 1 {
+			return nil, fmt.Errorf("prometheus query %q returned %d series, expected one", query, len(response.Data.Result))
+		}
+		for _, series := range response.Data.Result {
+			for _, point := range series.Values {
+				sample, err := parsePromPoint(point)
+				if err != nil {
+					return nil, fmt.Errorf("prometheus query %q: %w", query, err)
+				}
+				samples = append(samples, sample)
+			}
+		}
+	}
+	return samples, nil
+}
+
+func parsePromPoint(point [2]any) (promSample, error) {
+	timestamp, ok := point[0].(float64)
+	if !ok {
+		return promSample{}, fmt.Errorf("invalid sample timestamp %v", point[0])
+	}
+	raw, ok := point[1].(string)
+	if !ok {
+		return promSample{}, fmt.Errorf("invalid sample value %v", point[1])
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return promSample{}, fmt.Errorf("invalid sample value %q: %w", raw, err)
+	}
+	seconds, fraction := math.Modf(timestamp)
+	return promSample{Timestamp: time.Unix(int64(seconds), int64(fraction*1e9)).UTC(), Value: value}, nil
+}
+
+// BuildAvailabilityReport joins the health, slots behind and epoch series by timestamp.
+// Each health sample stands for one step of time.
+func BuildAvailabilityReport(config *ReportConfig, health, slotsBehind, epochs []promSample) *AvailabilityReport {
+	report := &AvailabilityReport{
+		From:        config.From,
+		To:          config.To,
+		StepSeconds: config.Step.Seconds(),
+		Selector:    config.Selector,
+		Incidents:   []Incident{},
+		Epochs:      []EpochSummary{},
+	}
+
+	behindAt := make(map[int64]float64, len(slotsBehind))
+	for _, sample := range slotsBehind {
+		behindAt[sample.Timestamp.Unix()] = sample.Value
+	}
+	epochAt := make(map[int64]int64, len(epochs))
+	for _, sample := range epochs {
+		epochAt[sample.Timestamp.Unix()] = int64(sample.Value)
+	}
+
+	sort.Slice(health, func(i, j int) bool { return health[i].Timestamp.Before(health[j].Timestamp) })
+
+	var (
+		healthy, behindSamples int
+		behindSum              float64
+		incident               *Incident
+		byEpoch                = make(map[int64]*epochAccumulator)
+	)
+	for _, sample := range health {
+		isHealthy := sample.Value >= 1
+		behind, hasBehind := behindAt[sample.Timestamp.Unix()]
+		if hasBehind {
+			behindSum += behind
+			behindSamples++
+			report.MaxSlotsBehind = math.Max(report.MaxSlotsBehind, behind)
+		}
+
+		if isHealthy {
+			healthy++
+			if incident != nil {
+				incident.End = sample.Timestamp
+				incident.Seconds = incident.End.Sub(incident.Start).Seconds()
+				report.Incidents = append(report.Incidents, *incident)
+				incident = nil
+			}
+		} else {
+			if incident == nil {
+				incident = &Incident{Start: sample.Timestamp}
+			}
+			incident.MaxSlotsBehind = math.Max(incident.MaxSlotsBehind, behind)
+		}
+
+		if epoch, ok := epochAt[sample.Timestamp.Unix()]; ok {
+			accumulator, exists := byEpoch[epoch]
+			if !exists {
+				accumulator = &epochAccumulator{summary: EpochSummary{Epoch: epoch, Start: sample.Timestamp}}
+				byEpoch[epoch] = accumulator
+			}
+			accumulator.summary.End = sample.Timestamp
+			accumulator.samples++
+			if isHealthy {
+				accumulator.healthy++
+			}
+			accumulator.behindSum += behind
+			accumulator.summary.MaxSlotsBehind = math.Max(accumulator.summary.MaxSlotsBehind, behind)
+		}
+	}
+	if incident != nil {
+		// still ongoing at the end of the report
+		incident.End = config.To
+		incident.Seconds = incident.End.Sub(incident.Start).Seconds()
+		report.Incidents = append(report.Incidents, *incident)
+	}
+
+	if len(health) > 0 {
+		report.Availability = float64(healthy) / float64(len(health))
+	}
+	report.TimeUnhealthySeconds = float64(len(health)-healthy) * config.Step.Seconds()
+	report.NoDataSeconds = math.Max(0, config.To.Sub(config.From).Seconds()-float64(len(health))*config.Step.Seconds())
+	if behindSamples > 0 {
+		report.AvgSlotsBehind = behindSum / float64(behindSamples)
+	}
+
+	for _, accumulator := range byEpoch {
+		summary := accumulator.summary
+		summary.Availability = float64(accumulator.healthy) / float64(accumulator.samples)
+		summary.TimeUnhealthySeconds = float64(accumulator.samples-accumulator.healthy) * config.Step.Seconds()
+		summary.AvgSlotsBehind = accumulator.behindSum / float64(accumulator.samples)
+		report.Epochs = append(report.Epochs, summary)
+	}
+	sort.Slice(report.Epochs, func(i, j int) bool { return report.Epochs[i].Epoch < report.Epochs[j].Epoch })
+
+	return report
+}
+
+// reportQuery reduces a metric to a single series; several exporters matching the
+// selector are combined pessimistically.
+func reportQuery(aggregation string, metric string, selector string) string {
+	return fmt.Sprintf("%s(%s{%s})", aggregation, metric, selector)
+}
+
+func runReport(ctx context.Context, args []string, out io.Writer) error {
+	config, err := NewReportConfigFromArgs(args)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: config.HttpTimeout}
+	health, err := config.queryRange(ctx, client, reportQuery("min", "solana_node_health", config.Selector))
+	if err != nil {
+		return err
+	}
+	slotsBehind, err := config.queryRange(ctx, client, reportQuery("max", "solana_node_num_slots_behind", config.Selector))
+	if err != nil {
+		return err
+	}
+	epochs, err := config.queryRange(ctx, client, reportQuery("max", "solana_network_epoch", config.Selector))
+	if err != nil {
+		return err
+	}
+
+	return writeReport(out, config.Format, BuildAvailabilityReport(config, health, slotsBehind, epochs))
+}
+
+func writeReport(out io.Writer, format string, report *AvailabilityReport) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case FormatHTML:
+		return htmlReportTemplate.Execute(out, report)
+	default:
+		return markdownReportTemplate.Execute(out, report)
+	}
+}
+
+var reportFuncs = map[string]any{
+	"percent": func(ratio float64) string { return strconv.FormatFloat(ratio*100, 'f', 3, 64) + "%" },
+	"duration": func(seconds float64) string {
+		return (time.Duration(seconds) * time.Second).String()
+	},
+	"number": func(value float64) string { return strconv.FormatFloat(value, 'f', 1, 64) },
+	"timestamp": func(value time.Time) string {
+		return value.UTC().Format(time.RFC3339)
+	},
+}
+
+// markdown goes through text/template: html escaping would mangle selectors like network="..."
+var markdownReportTemplate = texttemplate.Must(texttemplate.New("markdown").Funcs(reportFuncs).Parse(
+	`# Solana RPC node availability report
+
+- **Period:** {{timestamp .From}} to {{timestamp .To}}
+- **Selector:** ` + "`{{if .Selector}}{{.Selector}}{{else}}(all){{end}}`" + `
+
+| Availability | Time unhealthy | No data | Avg slots behind | Max slots behind | Incidents |
+|---|---|---|---|---|---|
+| {{percent .Availability}} | {{duration .TimeUnhealthySeconds}} | {{duration .NoDataSeconds}} | {{number .AvgSlotsBehind}} | {{number .MaxSlotsBehind}} | {{len .Incidents}} |
+
+## Incidents
+{{if .Incidents}}
+| Start | End | Duration | Max slots behind |
+|---|---|---|---|
+{{range .Incidents}}| {{timestamp .Start}} | {{timestamp .End}} | {{duration .Seconds}} | {{number .MaxSlotsBehind}} |
+{{end}}{{else}}
+No incidents.
+{{end}}
+## Epochs
+{{if .Epochs}}
+| Epoch | Start | End | Availability | Time unhealthy | Avg slots behind | Max slots behind |
+|---|---|---|---|---|---|---|
+{{range .Epochs}}| {{.Epoch}} | {{timestamp .Start}} | {{timestamp .End}} | {{percent .Availability}} | {{duration .TimeUnhealthySeconds}} | {{number .AvgSlotsBehind}} | {{number .MaxSlotsBehind}} |
+{{end}}{{else}}
+No epoch data.
+{{end}}`))
+
+var htmlReportTemplate = template.Must(template.New("html").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Solana RPC node availability report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: right; }
+th { background: #f4f4f4; }
+</style>
+</head>
+<body>
+<h1>Solana RPC node availability report</h1>
+<p>Period: {{timestamp .From}} to {{timestamp .To}}<br>Selector: <code>{{if .Selector}}{{.Selector}}{{else}}(all){{end}}</code></p>
+<table>
+<tr><th>Availability</th><th>Time unhealthy</th><th>No data</th><th>Avg slots behind</th><th>Max slots behind</th><th>Incidents</th></tr>
+<tr><td>{{percent .Availability}}</td><td>{{duration .TimeUnhealthySeconds}}</td><td>{{duration .NoDataSeconds}}</td><td>{{number .AvgSlotsBehind}}</td><td>{{number .MaxSlotsBehind}}</td><td>{{len .Incidents}}</td></tr>
+</table>
+<h2>Incidents</h2>
+{{if .Incidents}}<table>
+<tr><th>Start</th><th>End</th><th>Duration</th><th>Max slots behind</th></tr>
+{{range .Incidents}}<tr><td>{{timestamp .Start}}</td><td>{{timestamp .End}}</td><td>{{duration .Seconds}}</td><td>{{number .MaxSlotsBehind}}</td></tr>
+{{end}}</table>{{else}}<p>No incidents.</p>{{end}}
+<h2>Epochs</h2>
+{{if .Epochs}}<table>
+<tr><th>Epoch</th><th>Start</th><th>End</th><th>Availability</th><th>Time unhealthy</th><th>Avg slots behind</th><th>Max slots behind</th></tr>
+{{range .Epochs}}<tr><td>{{.Epoch}}</td><td>{{timestamp .Start}}</td><td>{{timestamp .End}}</td><td>{{percent .Availability}}</td><td>{{duration .TimeUnhealthySeconds}}</td><td>{{number .AvgSlotsBehind}}</td><td>{{number .MaxSlotsBehind}}</td></tr>
+{{end}}</table>{{else}}<p>No epoch data.</p>{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newPrometheusStandIn serves query_range for the report queries: the node is unhealthy
+// (100 slots behind) at steps 10-14 and 40-41, and epoch 500 turns into 501 at step 30.
+func newPrometheusStandIn(t *testing.T, from time.Time) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/query_range", r.URL.Path)
+		query := r.URL.Query()
+		start, _ := strconv.ParseInt(query.Get("start"), 10, 64)
+		end, _ := strconv.ParseInt(query.Get("end"), 10, 64)
+		step, _ := strconv.ParseInt(query.Get("step"), 10, 64)
+
+		var values [][2]any
+		for ts := start; ts <= end; ts += step {
+			index := (ts - from.Unix()) / step
+			unhealthy := (index >= 10 && index < 15) || (index >= 40 && index < 42)
+			var value float64
+			switch {
+			case strings.Contains(query.Get("query"), "solana_node_health"):
+				if !unhealthy {
+					value = 1
+				}
+			case strings.Contains(query.Get("query"), "solana_node_num_slots_behind"):
+				if unhealthy {
+					value = 100
+				}
+			case strings.Contains(query.Get("query"), "solana_network_epoch"):
+				value = 500
+				if index >= 30 {
+					value = 501
+				}
+			}
+			values = append(values, [2]any{ts, strconv.FormatFloat(value, 'f', -1, 64)})
+		}
+
+		response := map[string]any{
+			"status": "success",
+			"data": map[string]any{
+				"resultType": "matrix",
+				"result":     []map[string]any{{"metric": map[string]string{}, "values": values}},
+			},
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(response))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewReportConfigFromArgs(t *testing.T) {
+	config, err := NewReportConfigFromArgs([]string{"-from", "2024-10-01", "-to", "2024-11-01T00:00:00Z", "-format", "html"})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC), config.From)
+	assert.Equal(t, time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), config.To)
+	assert.Equal(t, FormatHTML, config.Format)
+	assert.Equal(t, time.Minute, config.Step)
+
+	config, err = NewReportConfigFromArgs([]string{"-to", "2024-11-01"})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 10, 2, 0, 0, 0, 0, time.UTC), config.From)
+
+	_, err = NewReportConfigFromArgs([]string{"-from", "2024-11-01", "-to", "2024-10-01"})
+	assert.Error(t, err)
+	_, err = NewReportConfigFromArgs([]string{"-format", "pdf"})
+	assert.Error(t, err)
+}
+
+func TestRunReport(t *testing.T) {
+	from := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(59 * time.Minute)
+	server := newPrometheusStandIn(t, from)
+	args := []string{
+		"-prometheus-url", server.URL,
+		"-from", from.Format(time.RFC3339),
+		"-to", to.Format(time.RFC3339),
+		"-format", "json",
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, runReport(context.Background(), args, &out))
+
+	var report AvailabilityReport
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	assert.InDelta(t, 53.0/60.0, report.Availability, 1e-9)
+	assert.Equal(t, 7*60.0, report.TimeUnhealthySeconds)
+	assert.Equal(t, 100.0, report.MaxSlotsBehind)
+	assert.InDelta(t, 700.0/60.0, report.AvgSlotsBehind, 1e-9)
+	if assert.Len(t, report.Incidents, 2) {
+		assert.Equal(t, from.Add(10*time.Minute), report.Incidents[0].Start)
+		assert.Equal(t, 5*60.0, report.Incidents[0].Seconds)
+		assert.Equal(t, 100.0, report.Incidents[0].MaxSlotsBehind)
+		assert.Equal(t, 2*60.0, report.Incidents[1].Seconds)
+	}
+	if assert.Len(t, report.Epochs, 2) {
+		assert.Equal(t, int64(500), report.Epochs[0].Epoch)
+		assert.InDelta(t, 25.0/30.0, report.Epochs[0].Availability, 1e-9)
+		assert.Equal(t, int64(501), report.Epochs[1].Epoch)
+		assert.InDelta(t, 28.0/30.0, report.Epochs[1].Availability, 1e-9)
+	}
+
+	for _, format := range []string{FormatMarkdown, FormatHTML} {
+		out.Reset()
+		assert.NoError(t, runReport(context.Background(), append(args, "-format", format, "-selector", `network="mainnet-beta"`), &out))
+		assert.Contains(t, out.String(), "88.333%")
+		assert.Contains(t, out.String(), "5m0s")
+		if format == FormatMarkdown {
+			assert.Contains(t, out.String(), `network="mainnet-beta"`)
+			assert.Contains(t, out.String(), fmt.Sprintf("| 500 | %s |", from.Format(time.RFC3339)))
+		} else {
+			assert.Contains(t, out.String(), "<td>501</td>")
+		}
+	}
+}
+
+func TestQueryRange_Chunks(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"status":"success","data":{"resultType":"matrix","result":[]}}`)
+	}))
+	defer server.Close()
+
+	config := &ReportConfig{
+		PrometheusUrl: server.URL,
+		From:          time.Unix(0, 0),
+		To:            time.Unix(0, 0).Add(30 * 24 * time.Hour),
+		Step:          time.Minute,
+	}
+	samples, err := config.queryRange(context.Background(), server.Client(), "up")
+	assert.NoError(t, err)
+	assert.Empty(t, samples)
+	// 43,201 points at a one minute step need five queries of at most 10,000 steps
+	assert.Equal(t, 5, requests)
+}
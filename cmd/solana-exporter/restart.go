@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/naviat/solana-rpc-exporter/pkg/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	RestartAllow = "allow"
+	RestartDeny  = "deny"
+
+	RestartCheckLeader   = "leader_slots"
+	RestartCheckSnapshot = "local_snapshot"
+	RestartCheckEpoch    = "epoch_boundary"
+	RestartCheckPeers    = "healthy_peers"
+)
+
+type (
+	// RestartRules are the thresholds a restart is checked against, margins and ages in slots
+	RestartRules struct {
+		LeaderMarginSlots   int64
+		MaxSnapshotAgeSlots int64
+		EpochMarginSlots    int64
+		// MinHealthyPeers is how many of the configured peers must be healthy, the check is skipped if 0
+		MinHealthyPeers int
+	}
+
+	// RestartCheck is the outcome of a single rule
+	RestartCheck struct {
+		Name    string `json:"name"`
+		Passed  bool   `json:"passed"`
+		Skipped bool   `json:"skipped,omitempty"`
+		Reason  string `json:"reason"`
+	}
+
+	// RestartDecision is the answer served by /api/v1/safe-to-restart
+	RestartDecision struct {
+		Decision    string         `json:"decision"`
+		Reasons     []string       `json:"reasons"`
+		Identity    string         `json:"identity,omitempty"`
+		Slot        int64          `json:"slot,omitempty"`
+		Checks      []RestartCheck `json:"checks"`
+		EvaluatedAt time.Time      `json:"evaluatedAt"`
+	}
+
+	// restartInputs are the facts a decision is made from, also exported as metrics
+	restartInputs struct {
+		err                error
+		identity           string
+		slot               int64
+		slotsUntilEpochEnd int64
+		// slotsUntilLeader is +Inf when the identity has no leader slots left in the epoch
+		slotsUntilLeader float64
+		// snapshotSlot is 0 when no local snapshot was found
+		snapshotSlot   int64
+		ledgerAttached bool
+		healthyPeers   int
+	}
+
+	// RestartGuard decides whether the node can be restarted without skipping its own
+	// leader slots, falling far behind on startup or restarting across an epoch boundary.
+	RestartGuard struct {
+		client *rpc.Client
+		logger *zap.SugaredLogger
+		config *ExporterConfig
+		// ledger is nil when the exporter is not co-located with the node
+		ledger *LedgerInspector
+		// peers are the other fleet nodes that must stay up while this one restarts
+		peers map[string]*rpc.Client
+
+		// the leader schedule only changes once per epoch
+		mu               sync.Mutex
+		scheduleEpoch    int64
+		scheduleIdentity string
+		leaderSlots      []int64
+
+		RestartSafe        *GaugeDesc
+		SlotsUntilLeader   *GaugeDesc
+		SnapshotAgeSlots   *GaugeDesc
+		SlotsUntilEpochEnd *GaugeDesc
+		HealthyPeers       *GaugeDesc
+	}
+)
+
+func NewRestartGuard(client *rpc.Client, config *ExporterConfig, ledger *LedgerInspector) *RestartGuard {
+	peers := make(map[string]*rpc.Client, len(config.RestartPeers))
+	for _, peer := range config.RestartPeers {
+		peers[peer] = rpc.NewRPCClient(peer, config.HttpTimeout)
+	}
+	return &RestartGuard{
+		client: client,
+		logger: slog.Get(),
+		config: config,
+		ledger: ledger,
+		peers:  peers,
+
+		RestartSafe: NewGaugeDesc(
+			"solana_restart_safe",
+			"Whether restarting the node is currently considered safe (1 = allow, 0 = deny)",
+			NetworkLabel,
+		),
+		SlotsUntilLeader: NewGaugeDesc(
+			"solana_restart_slots_until_leader",
+			"Slots until the node's next leader slot in the current epoch (+Inf if none are left)",
+			NetworkLabel,
+		),
+		SnapshotAgeSlots: NewGaugeDesc(
+			"solana_restart_snapshot_age_slots",
+			"Slots between the current slot and the newest local snapshot archive",
+			NetworkLabel,
+		),
+		SlotsUntilEpochEnd: NewGaugeDesc(
+			"solana_restart_slots_until_epoch_end",
+			"Slots left until the next epoch boundary",
+			NetworkLabel,
+		),
+		HealthyPeers: NewGaugeDesc(
+			"solana_restart_healthy_peers",
+			"Number of configured peer nodes answering getHealth with ok",
+			NetworkLabel,
+		),
+	}
+}
+
+// gatherInputs fetches everything a decision needs from the node and the local ledger
+func (g *RestartGuard) gatherInputs(ctx context.Context) restartInputs {
+	inputs := restartInputs{slotsUntilLeader: math.Inf(1)}
+
+	epochInfo, err := g.client.GetEpochInfo(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		inputs.err = fmt.Errorf("failed to get epoch info: %w", err)
+		return inputs
+	}
+	inputs.slot = epochInfo.AbsoluteSlot
+	inputs.slotsUntilEpochEnd = epochInfo.SlotsInEpoch - epochInfo.SlotIndex
+
+	identity, err := g.client.GetIdentity(ctx)
+	if err != nil {
+		inputs.err = fmt.Errorf("failed to get node identity: %w", err)
+		return inputs
+	}
+	inputs.identity = identity
+
+	leaderSlots, err := g.getLeaderSlots(ctx, epochInfo, identity)
+	if err != nil {
+		inputs.err = fmt.Errorf("failed to get leader schedule: %w", err)
+		return inputs
+	}
+	next := sort.Search(len(leaderSlots), func(i int) bool { return leaderSlots[i] >= inputs.slot })
+	if next < len(leaderSlots) {
+		inputs.slotsUntilLeader = float64(leaderSlots[next] - inputs.slot)
+	}
+
+	if g.ledger != nil {
+		inputs.ledgerAttached = true
+		for _, snapshotType := range []string{SnapshotFull, SnapshotIncrement} {
+			if snapshot := g.ledger.LatestSnapshot(snapshotType); snapshot != nil && snapshot.Slot > inputs.snapshotSlot {
+				inputs.snapshotSlot = snapshot.Slot
+			}
+		}
+	}
+
+	inputs.healthyPeers = g.countHealthyPeers(ctx)
+	return inputs
+}
+
+// countHealthyPeers asks every peer for getHealth concurrently; unreachable peers count as unhealthy
+func (g *RestartGuard) countHealthyPeers(ctx context.Context) int {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		healthy int
+	)
+	for url, peer := range g.peers {
+		wg.Add(1)
+		go func(url string, peer *rpc.Client) {
+			defer wg.Done()
+			// called directly, as GetHealth caches healthy answers and would hide a peer that just fell behind
+			if _, err := peer.Call(ctx, "getHealth", nil); err != nil {
+				g.logger.Debugw("Restart peer is unhealthy", "peer", rpc.EndpointLabel(url), "error", err)
+				return
+			}
+			mu.Lock()
+			healthy++
+			mu.Unlock()
+		}(url, peer)
+	}
+	wg.Wait()
+	return healthy
+}
+
+// getLeaderSlots returns the absolute leader slots of identity in the current epoch, sorted
+func (g *RestartGuard) getLeaderSlots(ctx context.Context, epochInfo *rpc.EpochInfo, identity string) ([]int64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.leaderSlots != nil && g.scheduleEpoch == epochInfo.Epoch && g.scheduleIdentity == identity {
+		return g.leaderSlots, nil
+	}
+
+	relative, err := g.client.GetLeaderSchedule(ctx, rpc.CommitmentConfirmed, identity)
+	if err != nil {
+		return nil, err
+	}
+	firstSlot, _ := GetEpochBounds(epochInfo)
+	leaderSlots := make([]int64, len(relative))
+	for i, index := range relative {
+		leaderSlots[i] = firstSlot + index
+	}
+	sort.Slice(leaderSlots, func(i, j int) bool { return leaderSlots[i] < leaderSlots[j] })
+
+	g.scheduleEpoch = epochInfo.Epoch
+	g.scheduleIdentity = identity
+	g.leaderSlots = leaderSlots
+	return leaderSlots, nil
+}
+
+// decide applies the rules to the inputs; any failed check denies the restart
+func decide(rules RestartRules, inputs restartInputs, now time.Time) *RestartDecision {
+	decision := &RestartDecision{
+		Decision:    RestartAllow,
+		Reasons:     []string{},
+		Identity:    inputs.identity,
+		Slot:        inputs.slot,
+		Checks:      []RestartCheck{},
+		EvaluatedAt: now,
+	}
+	if inputs.err != nil {
+		// without the inputs nothing can be vouched for
+		decision.Decision = RestartDeny
+		decision.Reasons = append(decision.Reasons, inputs.err.Error())
+		return decision
+	}
+
+	leader := RestartCheck{Name: RestartCheckLeader, Passed: true}
+	if math.IsInf(inputs.slotsUntilLeader, 1) {
+		leader.Reason = "no leader slots left in the current epoch"
+	} else {
+		leader.Passed = inputs.slotsUntilLeader > float64(rules.LeaderMarginSlots)
+		leader.Reason = fmt.Sprintf(
+			"next leader slot in %d slots (margin %d)", int64(inputs.slotsUntilLeader), rules.LeaderMarginSlots,
+		)
+	}
+
+	snapshot := RestartCheck{Name: RestartCheckSnapshot}
+	switch {
+	case !inputs.ledgerAttached:
+		snapshot.Passed = true
+		snapshot.Skipped = true
+		snapshot.Reason = "local ledger not inspected (--ledger-path not set)"
+	case inputs.snapshotSlot == 0:
+		snapshot.Reason = "no local snapshot archive found"
+	default:
+		age := inputs.slot - inputs.snapshotSlot
+		snapshot.Passed = age <= rules.MaxSnapshotAgeSlots
+		snapshot.Reason = fmt.Sprintf(
+			"newest local snapshot is %d slots old (maximum %d)", age, rules.MaxSnapshotAgeSlots,
+		)
+	}
+
+	epoch := RestartCheck{
+		Name:   RestartCheckEpoch,
+		Passed: inputs.slotsUntilEpochEnd > rules.EpochMarginSlots,
+		Reason: fmt.Sprintf(
+			"epoch ends in %d slots (margin %d)", inputs.slotsUntilEpochEnd, rules.EpochMarginSlots,
+		),
+	}
+
+	peers := RestartCheck{Name: RestartCheckPeers}
+	if rules.MinHealthyPeers == 0 {
+		peers.Passed = true
+		peers.Skipped = true
+		peers.Reason = "no healthy peers required (--restart-min-healthy-peers not set)"
+	} else {
+		peers.Passed = inputs.healthyPeers >= rules.MinHealthyPeers
+		peers.Reason = fmt.Sprintf("%d peers healthy (minimum %d)", inputs.healthyPeers, rules.MinHealthyPeers)
+	}
+
+	for _, check := range []RestartCheck{leader, snapshot, epoch, peers} {
+		decision.Checks = append(decision.Checks, check)
+		if !check.Passed {
+			decision.Decision = RestartDeny
+			decision.Reasons = append(decision.Reasons, check.Reason)
+		}
+	}
+	return decision
+}
+
+// Evaluate decides whether the node can be restarted right now
+func (g *RestartGuard) Evaluate(ctx context.Context) *RestartDecision {
+	return decide(g.config.RestartRules, g.gatherInputs(ctx), time.Now())
+}
+
+// ServeHTTP answers with the decision as JSON: 200 to allow a restart, 409 to deny it
+func (g *RestartGuard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	decision := g.Evaluate(r.Context())
+	if decision.Decision == RestartDeny {
+		g.logger.Infow("Restart denied", "reasons", decision.Reasons)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if decision.Decision == RestartAllow {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusConflict)
+	}
+	if err := json.NewEncoder(w).Encode(decision); err != nil {
+		g.logger.Errorw("Failed to write restart decision", "error", err)
+	}
+}
+
+func (g *RestartGuard) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.RestartSafe.Desc
+	ch <- g.SlotsUntilLeader.Desc
+	ch <- g.SnapshotAgeSlots.Desc
+	ch <- g.SlotsUntilEpochEnd.Desc
+	ch <- g.HealthyPeers.Desc
+}
+
+func (g *RestartGuard) Collect(ch chan<- prometheus.Metric) {
+	ctx := context.Background()
+	inputs := g.gatherInputs(ctx)
+	decision := decide(g.config.RestartRules, inputs, time.Now())
+
+	safe := 0.0
+	if decision.Decision == RestartAllow {
+		safe = 1
+	}
+	ch <- g.RestartSafe.MustNewConstMetric(safe, g.config.NetworkName)
+
+	if inputs.err != nil {
+		g.logger.Errorw("Failed to gather restart decision inputs", "error", inputs.err)
+		return
+	}
+	ch <- g.SlotsUntilLeader.MustNewConstMetric(inputs.slotsUntilLeader, g.config.NetworkName)
+	ch <- g.SlotsUntilEpochEnd.MustNewConstMetric(float64(inputs.slotsUntilEpochEnd), g.config.NetworkName)
+	if len(g.peers) > 0 {
+		ch <- g.HealthyPeers.MustNewConstMetric(float64(inputs.healthyPeers), g.config.NetworkName)
+	}
+	if inputs.snapshotSlot > 0 {
+		ch <- g.SnapshotAgeSlots.MustNewConstMetric(float64(inputs.slot-inputs.snapshotSlot), g.config.NetworkName)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+var testRestartRules = RestartRules{LeaderMarginSlots: 300, MaxSnapshotAgeSlots: 50000, EpochMarginSlots: 1500}
+
+func TestDecide(t *testing.T) {
+	tests := []struct {
+		name     string
+		inputs   restartInputs
+		decision string
+		failed   []string
+	}{
+		{
+			name: "all clear",
+			inputs: restartInputs{
+				slot: 100_000, slotsUntilEpochEnd: 200_000, slotsUntilLeader: 5000,
+				snapshotSlot: 90_000, ledgerAttached: true,
+			},
+			decision: RestartAllow,
+		},
+		{
+			name: "no leader slots and no ledger",
+			inputs: restartInputs{
+				slot: 100_000, slotsUntilEpochEnd: 200_000, slotsUntilLeader: math.Inf(1),
+			},
+			decision: RestartAllow,
+		},
+		{
+			name: "leader slots imminent",
+			inputs: restartInputs{
+				slot: 100_000, slotsUntilEpochEnd: 200_000, slotsUntilLeader: 40,
+			},
+			decision: RestartDeny,
+			failed:   []string{RestartCheckLeader},
+		},
+		{
+			name: "stale snapshot near the epoch boundary",
+			inputs: restartInputs{
+				slot: 100_000, slotsUntilEpochEnd: 1000, slotsUntilLeader: math.Inf(1),
+				snapshotSlot: 10_000, ledgerAttached: true,
+			},
+			decision: RestartDeny,
+			failed:   []string{RestartCheckSnapshot, RestartCheckEpoch},
+		},
+		{
+			name: "no snapshot",
+			inputs: restartInputs{
+				slot: 100_000, slotsUntilEpochEnd: 200_000, slotsUntilLeader: math.Inf(1), ledgerAttached: true,
+			},
+			decision: RestartDeny,
+			failed:   []string{RestartCheckSnapshot},
+		},
+		{
+			name:     "inputs unavailable",
+			inputs:   restartInputs{err: errors.New("failed to get epoch info: connection refused")},
+			decision: RestartDeny,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := decide(testRestartRules, tt.inputs, time.Now())
+			assert.Equal(t, tt.decision, decision.Decision)
+
+			var failed []string
+			for _, check := range decision.Checks {
+				if !check.Passed {
+					failed = append(failed, check.Name)
+				}
+			}
+			assert.Equal(t, tt.failed, failed)
+			if tt.decision == RestartDeny {
+				assert.NotEmpty(t, decision.Reasons)
+			} else {
+				assert.Empty(t, decision.Reasons)
+			}
+		})
+	}
+}
+
+func TestDecide_HealthyPeers(t *testing.T) {
+	rules := testRestartRules
+	rules.MinHealthyPeers = 2
+	inputs := restartInputs{slot: 100_000, slotsUntilEpochEnd: 200_000, slotsUntilLeader: math.Inf(1), healthyPeers: 1}
+
+	decision := decide(rules, inputs, time.Now())
+	assert.Equal(t, RestartDeny, decision.Decision)
+	assert.Equal(t, []string{"1 peers healthy (minimum 2)"}, decision.Reasons)
+
+	inputs.healthyPeers = 2
+	assert.Equal(t, RestartAllow, decide(rules, inputs, time.Now()).Decision)
+}
+
+func TestRestartGuard(t *testing.T) {
+	_, client := rpc.NewMockClient(t, map[string]any{
+		"getEpochInfo": map[string]int{
+			"absoluteSlot":     166_598,
+			"blockHeight":      166_500,
+			"epoch":            27,
+			"slotIndex":        2790,
+			"slotsInEpoch":     8192,
+			"transactionCount": 22_661_093,
+		},
+		"getIdentity": map[string]string{"identity": "nodeA"},
+		// the epoch starts at slot 163808, so the next leader slot is 166608
+		"getLeaderSchedule": map[string]any{"nodeA": []int64{100, 101, 2800, 2801}},
+	})
+
+	ledger := t.TempDir()
+	writeTestFile(t, filepath.Join(ledger, "snapshot-160000-Hash1.tar.zst"), 10)
+	inspector := NewLedgerInspector(&ExporterConfig{NetworkName: "mainnet-beta", LedgerPath: ledger})
+	inspector.inspect(time.Now())
+
+	config := &ExporterConfig{NetworkName: "mainnet-beta", RestartRules: testRestartRules}
+	guard := NewRestartGuard(client, config, inspector)
+
+	recorder := httptest.NewRecorder()
+	guard.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/safe-to-restart", nil))
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+
+	var decision RestartDecision
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&decision))
+	assert.Equal(t, RestartDeny, decision.Decision)
+	assert.Equal(t, "nodeA", decision.Identity)
+	assert.Equal(t, []string{"next leader slot in 10 slots (margin 300)"}, decision.Reasons)
+	assert.Len(t, decision.Checks, 4)
+
+	tests := []collectionTest{
+		guard.RestartSafe.makeCollectionTest(NewLV(0, "mainnet-beta")),
+		guard.SlotsUntilLeader.makeCollectionTest(NewLV(10, "mainnet-beta")),
+		guard.SnapshotAgeSlots.makeCollectionTest(NewLV(6598, "mainnet-beta")),
+		guard.SlotsUntilEpochEnd.makeCollectionTest(NewLV(5402, "mainnet-beta")),
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := testutil.CollectAndCompare(guard, strings.NewReader(test.ExpectedResponse), test.Name)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestRestartGuard_CountHealthyPeers(t *testing.T) {
+	healthy, _ := rpc.NewMockClient(t, map[string]any{"getHealth": "ok"})
+	unhealthy, _ := rpc.NewMockClient(t, map[string]any{
+		"getHealth": &rpc.RPCError{Code: rpc.NodeUnhealthyCode, Message: "Node is behind by 150 slots"},
+	})
+
+	config := &ExporterConfig{
+		NetworkName:  "mainnet-beta",
+		HttpTimeout:  time.Second,
+		RestartPeers: []string{healthy.URL(), unhealthy.URL(), "http://127.0.0.1:1"},
+	}
+	guard := NewRestartGuard(nil, config, nil)
+	assert.Equal(t, 1, guard.countHealthyPeers(context.Background()))
+}
+
+func TestRestartGuard_CountHealthyPeersFallingBehind(t *testing.T) {
+	peer, _ := rpc.NewMockClient(t, map[string]any{"getHealth": "ok"})
+
+	config := &ExporterConfig{
+		NetworkName:  "mainnet-beta",
+		HttpTimeout:  time.Second,
+		RestartPeers: []string{peer.URL()},
+	}
+	guard := NewRestartGuard(nil, config, nil)
+	assert.Equal(t, 1, guard.countHealthyPeers(context.Background()))
+
+	peer.SetOpt(rpc.EasyResultsOpt, "getHealth", &rpc.RPCError{Code: rpc.NodeUnhealthyCode, Message: "Node is behind by 150 slots"})
+	assert.Equal(t, 0, guard.countHealthyPeers(context.Background()))
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/naviat/solana-rpc-exporter/pkg/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	// RollbackPollInterval is how often newly confirmed and finalized slots are checked
+	RollbackPollInterval = 2 * time.Second
+	// maxTrackedSlots bounds the confirmed slots awaiting finalization, and catch-up after a stall
+	maxTrackedSlots = 1024
+	// maxFinalizedAttempts is how often an unavailable finalized block is retried before giving up
+	maxFinalizedAttempts = 5
+)
+
+type (
+	confirmedSlot struct {
+		blockhash string
+		attempts  int
+	}
+
+	// RollbackDetector remembers the blockhash of every block seen at confirmed commitment and
+	// checks it again once the slot is finalized. A confirmed block that ends up skipped, or
+	// replaced by a different block, at finalized commitment is a rollback: something that was
+	// trusted at confirmed commitment was later undone.
+	RollbackDetector struct {
+		client *rpc.Client
+		logger *zap.SugaredLogger
+		config *ExporterConfig
+
+		// only touched by the watching goroutine
+		lastConfirmed int64
+		confirmed     map[int64]*confirmedSlot
+
+		SlotsChecked  *prometheus.CounterVec
+		Rollbacks     *prometheus.CounterVec
+		RolledBack    *prometheus.CounterVec
+		RollbackDepth *prometheus.GaugeVec
+	}
+)
+
+func NewRollbackDetector(client *rpc.Client, config *ExporterConfig) *RollbackDetector {
+	return &RollbackDetector{
+		client:    client,
+		logger:    slog.Get(),
+		config:    config,
+		confirmed: make(map[int64]*confirmedSlot),
+
+		SlotsChecked: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "solana_confirmed_slots_checked_total",
+				Help: "Confirmed blocks whose blockhash was checked again at finalized commitment",
+			},
+			[]string{NetworkLabel},
+		),
+		Rollbacks: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "solana_confirmed_rollbacks_total",
+				Help: "Rollbacks of confirmed blocks, each covering one or more consecutive confirmed slots",
+			},
+			[]string{NetworkLabel},
+		),
+		RolledBack: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "solana_confirmed_rolled_back_slots_total",
+				Help: "Confirmed blocks that were skipped or replaced at finalized commitment",
+			},
+			[]string{NetworkLabel},
+		),
+		RollbackDepth: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "solana_confirmed_rollback_depth_slots",
+				Help: "Number of confirmed blocks undone by the most recent rollback",
+			},
+			[]string{NetworkLabel},
+		),
+	}
+}
+
+func (d *RollbackDetector) WatchRollbacks(ctx context.Context) error {
+	ticker := time.NewTicker(RollbackPollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *RollbackDetector) poll(ctx context.Context) {
+	confirmedSlot, err := d.client.GetSlot(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		d.logger.Errorw("Failed to get confirmed slot", "error", err)
+		return
+	}
+	d.recordConfirmed(ctx, confirmedSlot)
+
+	finalizedSlot, err := d.client.GetSlot(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		d.logger.Errorw("Failed to get finalized slot", "error", err)
+		return
+	}
+	d.checkFinalized(ctx, finalizedSlot)
+}
+
+// recordConfirmed remembers the blockhashes of the confirmed blocks up to slot
+func (d *RollbackDetector) recordConfirmed(ctx context.Context, slot int64) {
+	first := d.lastConfirmed + 1
+	if d.lastConfirmed == 0 || slot-first >= maxTrackedSlots {
+		first = slot
+	}
+	for s := first; s <= slot && len(d.confirmed) < maxTrackedSlots; s++ {
+		block, err := d.client.GetBlock(ctx, rpc.CommitmentConfirmed, s, "none")
+		if err != nil {
+			// slots off the confirmed fork are skipped or unavailable; there is nothing to remember
+			if !rpc.IsSlotSkipped(err) && !rpc.IsBlockNotAvailable(err) {
+				d.logger.Errorw("Failed to get confirmed block", "slot", s, "error", err)
+				return
+			}
+			continue
+		}
+		d.confirmed[s] = &confirmedSlot{blockhash: block.Blockhash}
+	}
+	if slot > d.lastConfirmed {
+		d.lastConfirmed = slot
+	}
+}
+
+// checkFinalized compares the remembered blocks up to slot with their finalized versions.
+// Rolled back blocks with no intact confirmed block between them count as one rollback.
+func (d *RollbackDetector) checkFinalized(ctx context.Context, slot int64) {
+	var due []int64
+	for s := range d.confirmed {
+		if s <= slot {
+			due = append(due, s)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i] < due[j] })
+
+	var rollback, checked []int64
+	finalizedHashes := make(map[int64]string)
+	for _, s := range due {
+		block, err := d.client.GetBlock(ctx, rpc.CommitmentFinalized, s, "none")
+		switch {
+		case err == nil && block.Blockhash == d.confirmed[s].blockhash:
+			d.reportRollback(rollback, finalizedHashes)
+			rollback = nil
+		case err == nil:
+			rollback = append(rollback, s)
+			finalizedHashes[s] = block.Blockhash
+		case rpc.IsSlotSkipped(err):
+			rollback = append(rollback, s)
+			// empty where the slot ended up skipped
+			finalizedHashes[s] = ""
+		default:
+			d.confirmed[s].attempts++
+			if d.confirmed[s].attempts < maxFinalizedAttempts {
+				continue
+			}
+			d.logger.Warnw("Giving up on checking confirmed block at finalized commitment", "slot", s, "error", err)
+			delete(d.confirmed, s)
+			continue
+		}
+		d.SlotsChecked.WithLabelValues(d.config.NetworkName).Inc()
+		checked = append(checked, s)
+	}
+	d.reportRollback(rollback, finalizedHashes)
+
+	for _, s := range checked {
+		delete(d.confirmed, s)
+	}
+}
+
+// reportRollback counts and logs one rollback of the given confirmed slots
+func (d *RollbackDetector) reportRollback(slots []int64, finalizedHashes map[int64]string) {
+	if len(slots) == 0 {
+		return
+	}
+	d.Rollbacks.WithLabelValues(d.config.NetworkName).Inc()
+	d.RolledBack.WithLabelValues(d.config.NetworkName).Add(float64(len(slots)))
+	d.RollbackDepth.WithLabelValues(d.config.NetworkName).Set(float64(len(slots)))
+
+	confirmedHashes := make([]string, len(slots))
+	replacedBy := make([]string, len(slots))
+	for i, s := range slots {
+		confirmedHashes[i] = d.confirmed[s].blockhash
+		replacedBy[i] = finalizedHashes[s]
+	}
+	d.logger.Errorw("Confirmed blocks were rolled back",
+		"slots", slots,
+		"depth", len(slots),
+		"confirmed_blockhashes", confirmedHashes,
+		"finalized_blockhashes", replacedBy,
+	)
+}
+
+func (d *RollbackDetector) Describe(ch chan<- *prometheus.Desc) {
+	d.SlotsChecked.Describe(ch)
+	d.Rollbacks.Describe(ch)
+	d.RolledBack.Describe(ch)
+	d.RollbackDepth.Describe(ch)
+}
+
+func (d *RollbackDetector) Collect(ch chan<- prometheus.Metric) {
+	d.SlotsChecked.Collect(ch)
+	d.Rollbacks.Collect(ch)
+	d.RolledBack.Collect(ch)
+	d.RollbackDepth.Collect(ch)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollbackDetector(t *testing.T) {
+	server, client := rpc.NewMockClient(t, map[string]any{})
+	for slot := int64(100); slot <= 106; slot++ {
+		server.SetOpt(rpc.BlockOpt, slot, map[string]any{"blockhash": fmt.Sprintf("hash%d", slot)})
+	}
+	server.SetOpt(rpc.BlockOpt, int64(103), &rpc.RPCError{Code: rpc.SlotSkippedCode, Message: "Slot 103 was skipped"})
+
+	detector := NewRollbackDetector(client, &ExporterConfig{NetworkName: "mainnet-beta"})
+	ctx := context.Background()
+	detector.lastConfirmed = 99
+	detector.recordConfirmed(ctx, 106)
+	assert.Len(t, detector.confirmed, 6)
+	assert.Equal(t, int64(106), detector.lastConfirmed)
+
+	// at finalized, 101 and 102 were replaced by another fork and 105 was dropped
+	server.SetOpt(rpc.BlockOpt, int64(101), map[string]any{"blockhash": "forkB101"})
+	server.SetOpt(rpc.BlockOpt, int64(102), &rpc.RPCError{Code: rpc.SlotSkippedCode, Message: "Slot 102 was skipped"})
+	server.SetOpt(rpc.BlockOpt, int64(105), &rpc.RPCError{Code: rpc.SlotSkippedCode, Message: "Slot 105 was skipped"})
+	server.SetOpt(rpc.BlockOpt, int64(106), &rpc.RPCError{Code: rpc.BlockNotAvailableCode, Message: "Block not available"})
+	detector.checkFinalized(ctx, 106)
+
+	assert.Equal(t, 5.0, testutil.ToFloat64(detector.SlotsChecked.WithLabelValues("mainnet-beta")))
+	// 104 is intact, splitting the rolled back blocks into two rollbacks
+	assert.Equal(t, 2.0, testutil.ToFloat64(detector.Rollbacks.WithLabelValues("mainnet-beta")))
+	assert.Equal(t, 3.0, testutil.ToFloat64(detector.RolledBack.WithLabelValues("mainnet-beta")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(detector.RollbackDepth.WithLabelValues("mainnet-beta")))
+
+	// the unavailable block is retried a few times before it is given up on
+	if assert.Len(t, detector.confirmed, 1) {
+		assert.Equal(t, 1, detector.confirmed[106].attempts)
+	}
+	for i := 1; i < maxFinalizedAttempts; i++ {
+		detector.checkFinalized(ctx, 106)
+	}
+	assert.Empty(t, detector.confirmed)
+	assert.Equal(t, 5.0, testutil.ToFloat64(detector.SlotsChecked.WithLabelValues("mainnet-beta")))
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	EndpointLabel = "endpoint"
+	PhaseLabel    = "phase"
+	ReusedLabel   = "reused"
+	PriorityLabel = "priority"
+	OutcomeLabel  = "outcome"
+)
+
+// RPCTraceMetrics implements rpc.TraceObserver and exports the observed HTTP phases
+type RPCTraceMetrics struct {
+	PhaseDuration *prometheus.HistogramVec
+	Connections   *prometheus.CounterVec
+}
+
+func NewRPCTraceMetrics() *RPCTraceMetrics {
+	return &RPCTraceMetrics{
+		PhaseDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name: "solana_rpc_request_phase_duration_seconds",
+				Help: "Duration of each HTTP phase (dns, connect, tls, first_byte, body_read) of RPC requests, " +
+					"by request outcome (success, rpc_error, timeout or error)",
+				Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+			},
+			[]string{EndpointLabel, PhaseLabel, OutcomeLabel},
+		),
+		Connections: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "solana_rpc_connections_total",
+				Help: "Number of connections used for RPC requests, by whether an idle connection was reused",
+			},
+			[]string{EndpointLabel, ReusedLabel},
+		),
+	}
+}
+
+func (m *RPCTraceMetrics) ObservePhase(endpoint string, phase string, outcome string, duration time.Duration) {
+	m.PhaseDuration.WithLabelValues(endpoint, phase, outcome).Observe(duration.Seconds())
+}
+
+func (m *RPCTraceMetrics) ObserveConnection(endpoint string, reused bool) {
+	m.Connections.WithLabelValues(endpoint, strconv.FormatBool(reused)).Inc()
+}
+
+func (m *RPCTraceMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.PhaseDuration.Describe(ch)
+	m.Connections.Describe(ch)
+}
+
+func (m *RPCTraceMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.PhaseDuration.Collect(ch)
+	m.Connections.Collect(ch)
+}
+
+// SchedulerMetrics implements rpc.SchedulerObserver and exports queue state per priority class
+type SchedulerMetrics struct {
+	scheduler *rpc.Scheduler
+
+	QueueDepth *GaugeDesc
+	InFlight   *GaugeDesc
+	QueueWait  *prometheus.HistogramVec
+	Rejected   *prometheus.CounterVec
+}
+
+func NewSchedulerMetrics(scheduler *rpc.Scheduler) *SchedulerMetrics {
+	return &SchedulerMetrics{
+		scheduler: scheduler,
+		QueueDepth: NewGaugeDesc(
+			"solana_rpc_scheduler_queue_depth",
+			"Number of RPC calls waiting for a slot, by priority class",
+			PriorityLabel,
+		),
+		InFlight: NewGaugeDesc(
+			"solana_rpc_scheduler_in_flight",
+			"Number of running RPC calls, by priority class",
+			PriorityLabel,
+		),
+		QueueWait: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "solana_rpc_scheduler_wait_seconds",
+				Help:    "Time RPC calls spent queued before being sent, by priority class",
+				Buckets: []float64{.001, .005, .01, .05, .1, .5, 1, 5, 10, 30, 60},
+			},
+			[]string{PriorityLabel},
+		),
+		Rejected: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "solana_rpc_scheduler_rejected_total",
+				Help: "Number of RPC calls dropped after exceeding their queue deadline or being cancelled, by priority class",
+			},
+			[]string{PriorityLabel},
+		),
+	}
+}
+
+func (m *SchedulerMetrics) ObserveWait(priority rpc.Priority, wait time.Duration, admitted bool) {
+	m.QueueWait.WithLabelValues(priority.String()).Observe(wait.Seconds())
+	if !admitted {
+		m.Rejected.WithLabelValues(priority.String()).Inc()
+	}
+}
+
+func (m *SchedulerMetrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.QueueDepth.Desc
+	ch <- m.InFlight.Desc
+	m.QueueWait.Describe(ch)
+	m.Rejected.Describe(ch)
+}
+
+func (m *SchedulerMetrics) Collect(ch chan<- prometheus.Metric) {
+	for _, priority := range rpc.Priorities() {
+		ch <- m.QueueDepth.MustNewConstMetric(float64(m.scheduler.QueueDepth(priority)), priority.String())
+		ch <- m.InFlight.MustNewConstMetric(float64(m.scheduler.InFlight(priority)), priority.String())
+	}
+	m.QueueWait.Collect(ch)
+	m.Rejected.Collect(ch)
+}
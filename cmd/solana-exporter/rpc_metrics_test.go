@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRPCTraceMetrics(t *testing.T) {
+	metrics := NewRPCTraceMetrics()
+	metrics.ObservePhase("localhost:8899", rpc.PhaseConnect, rpc.OutcomeSuccess, 2*time.Millisecond)
+	metrics.ObservePhase("localhost:8899", rpc.PhaseFirstByte, rpc.OutcomeTimeout, 30*time.Millisecond)
+	metrics.ObserveConnection("localhost:8899", false)
+	metrics.ObserveConnection("localhost:8899", true)
+	metrics.ObserveConnection("localhost:8899", true)
+
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics, "solana_rpc_request_phase_duration_seconds"))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.Connections.WithLabelValues("localhost:8899", "true")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Connections.WithLabelValues("localhost:8899", "false")))
+}
+
+func TestSchedulerMetrics(t *testing.T) {
+	scheduler := rpc.NewScheduler(1, rpc.DefaultClassLimits)
+	metrics := NewSchedulerMetrics(scheduler)
+	scheduler.Observer = metrics
+
+	release, err := scheduler.Acquire(context.Background(), rpc.PriorityBulk)
+	assert.NoError(t, err)
+	defer release()
+
+	assert.Equal(t, 6, testutil.CollectAndCount(metrics, "solana_rpc_scheduler_queue_depth", "solana_rpc_scheduler_in_flight"))
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics, "solana_rpc_scheduler_wait_seconds"))
+
+	metrics.ObserveWait(rpc.PriorityBulk, time.Minute, false)
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Rejected.WithLabelValues("bulk")))
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// RuleRate is the per-second increase of a metric over the window, allowing for counter resets
+	RuleRate = "rate"
+	// RuleAvgOverTime is the average of a metric's values over the window
+	RuleAvgOverTime = "avg_over_time"
+	// RuleRatio is left divided by right
+	RuleRatio = "ratio"
+	// RuleDifference is left minus right
+	RuleDifference = "difference"
+)
+
+type (
+	// RecordingRule defines a derived series. rate and avg_over_time use Metric and Window;
+	// ratio and difference use Left and Right, matching series with identical labels; a Right
+	// metric with a single series is applied to every Left series.
+	RecordingRule struct {
+		Record string        `yaml:"record"`
+		Help   string        `yaml:"help"`
+		Type   string        `yaml:"type"`
+		Metric string        `yaml:"metric"`
+		Left   string        `yaml:"left"`
+		Right  string        `yaml:"right"`
+		Window time.Duration `yaml:"window"`
+		// Scale multiplies the result, e.g. to convert slots to hours; 1 if unset
+		Scale float64 `yaml:"scale"`
+	}
+
+	recordingRulesFile struct {
+		Rules []RecordingRule `yaml:"rules"`
+	}
+
+	// sample is an input series value at one evaluation
+	sample struct {
+		labels    map[string]string
+		value     float64
+		timestamp time.Time
+	}
+
+	// RuleEvaluator gathers the source metrics and appends the series derived by the recording
+	// rules, so consumers that cannot run PromQL get the same signals. Windowed rules are
+	// evaluated over the values the exporter held at previous gatherings.
+	RuleEvaluator struct {
+		source prometheus.Gatherer
+		rules  []RecordingRule
+		logger *zap.SugaredLogger
+
+		mu sync.Mutex
+		// history holds the samples of windowed rule inputs, by metric and series
+		history map[string]map[string][]sample
+	}
+)
+
+// LoadRecordingRules reads and validates a YAML file of recording rules
+func LoadRecordingRules(path string) ([]RecordingRule, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording rules: %w", err)
+	}
+	var file recordingRulesFile
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse recording rules %s: %w", path, err)
+	}
+
+	seen := make(map[string]bool)
+	for i := range file.Rules {
+		rule := &file.Rules[i]
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("invalid recording rule %d (%s): %w", i, rule.Record, err)
+		}
+		if seen[rule.Record] {
+			return nil, fmt.Errorf("duplicate recording rule %s", rule.Record)
+		}
+		seen[rule.Record] = true
+		if rule.Scale == 0 {
+			rule.Scale = 1
+		}
+		if rule.Help == "" {
+			rule.Help = fmt.Sprintf("Recording rule: %s", rule.describe())
+		}
+	}
+	return file.Rules, nil
+}
+
+func (r *RecordingRule) validate() error {
+	if r.Record == "" {
+		return fmt.Errorf("record name is required")
+	}
+	switch r.Type {
+	case RuleRate, RuleAvgOverTime:
+		if r.Metric == "" || r.Window <= 0 {
+			return fmt.Errorf("%s needs metric and a positive window", r.Type)
+		}
+	case RuleRatio, RuleDifference:
+		if r.Left == "" || r.Right == "" {
+			return fmt.Errorf("%s needs left and right", r.Type)
+		}
+	default:
+		return fmt.Errorf("unknown rule type %q", r.Type)
+	}
+	return nil
+}
+
+func (r *RecordingRule) describe() string {
+	switch r.Type {
+	case RuleRate, RuleAvgOverTime:
+		return fmt.Sprintf("%s(%s[%s])", r.Type, r.Metric, r.Window)
+	default:
+		return fmt.Sprintf("%s(%s, %s)", r.Type, r.Left, r.Right)
+	}
+}
+
+func NewRuleEvaluator(source prometheus.Gatherer, rules []RecordingRule) *RuleEvaluator {
+	return &RuleEvaluator{
+		source:  source,
+		rules:   rules,
+		logger:  slog.Get(),
+		history: make(map[string]map[string][]sample),
+	}
+}
+
+// Gather implements prometheus.Gatherer
+func (e *RuleEvaluator) Gather() ([]*dto.MetricFamily, error) {
+	families, err := e.source.Gather()
+	if families == nil {
+		return nil, err
+	}
+	derived := e.evaluate(families, time.Now())
+	families = append(families, derived...)
+	sort.Slice(families, func(i, j int) bool { return families[i].GetName() < families[j].GetName() })
+	return families, err
+}
+
+// evaluate derives the rule results from one gathering
+func (e *RuleEvaluator) evaluate(families []*dto.MetricFamily, now time.Time) []*dto.MetricFamily {
+	current := make(map[string][]sample)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			value, ok := metricValue(family.GetType(), metric)
+			if !ok {
+				continue
+			}
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			current[family.GetName()] = append(current[family.GetName()], sample{labels, value, now})
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.record(current, now)
+
+	var derived []*dto.MetricFamily
+	for _, rule := range e.rules {
+		if _, exists := current[rule.Record]; exists {
+			e.logger.Warnw("Recording rule shadows an existing metric, skipping", "record", rule.Record)
+			continue
+		}
+
+		var results []sample
+		switch rule.Type {
+		case RuleRate, RuleAvgOverTime:
+			results = e.evaluateWindow(rule, now)
+		case RuleRatio, RuleDifference:
+			results = evaluateBinary(rule, current[rule.Left], current[rule.Right])
+		}
+		if family := e.toFamily(rule, results); family != nil {
+			derived = append(derived, family)
+		}
+	}
+	return derived
+}
+
+// record keeps the samples of windowed rule inputs and drops those older than any window
+func (e *RuleEvaluator) record(current map[string][]sample, now time.Time) {
+	windows := make(map[string]time.Duration)
+	for _, rule := range e.rules {
+		if rule.Type == RuleRate || rule.Type == RuleAvgOverTime {
+			windows[rule.Metric] = max(windows[rule.Metric], rule.Window)
+		}
+	}
+
+	for metric, window := range windows {
+		if e.history[metric] == nil {
+			e.history[metric] = make(map[string][]sample)
+		}
+		for _, s := range current[metric] {
+			key := labelsKey(s.labels)
+			e.history[metric][key] = append(e.history[metric][key], s)
+		}
+		for key, samples := range e.history[metric] {
+			kept := samples[:0]
+			for _, s := range samples {
+				if now.Sub(s.timestamp) <= window {
+					kept = append(kept, s)
+				}
+			}
+			if len(kept) == 0 {
+				delete(e.history[metric], key)
+			} else {
+				e.history[metric][key] = kept
+			}
+		}
+	}
+}
+
+func (e *RuleEvaluator) evaluateWindow(rule RecordingRule, now time.Time) []sample {
+	var results []sample
+	for _, samples := range e.history[rule.Metric] {
+		var window []sample
+		for _, s := range samples {
+			if now.Sub(s.timestamp) <= rule.Window {
+				window = append(window, s)
+			}
+		}
+		if len(window) == 0 {
+			continue
+		}
+
+		last := window[len(window)-1]
+		switch rule.Type {
+		case RuleRate:
+			elapsed := last.timestamp.Sub(window[0].timestamp).Seconds()
+			if elapsed <= 0 {
+				continue
+			}
+			increase := 0.0
+			for i := 1; i < len(window); i++ {
+				if delta := window[i].value - window[i-1].value; delta >= 0 {
+					increase += delta
+				} else {
+					// counter reset: it restarted from zero
+					increase += window[i].value
+				}
+			}
+			results = append(results, sample{labels: last.labels, value: increase / elapsed})
+		case RuleAvgOverTime:
+			sum := 0.0
+			for _, s := range window {
+				sum += s.value
+			}
+			results = append(results, sample{labels: last.labels, value: sum / float64(len(window))})
+		}
+	}
+	return results
+}
+
+func evaluateBinary(rule RecordingRule, left, right []sample) []sample {
+	rightByLabels := make(map[string]float64)
+	for _, s := range right {
+		rightByLabels[labelsKey(s.labels)] = s.value
+	}
+
+	var results []sample
+	for _, l := range left {
+		r, ok := rightByLabels[labelsKey(l.labels)]
+		if !ok && len(right) == 1 {
+			r, ok = right[0].value, true
+		}
+		if !ok {
+			continue
+		}
+		switch rule.Type {
+		case RuleRatio:
+			if r == 0 {
+				continue
+			}
+			results = append(results, sample{labels: l.labels, value: l.value / r})
+		case RuleDifference:
+			results = append(results, sample{labels: l.labels, value: l.value - r})
+		}
+	}
+	return results
+}
+
+// toFamily exports results through a GaugeDesc like any other metric of the exporter
+func (e *RuleEvaluator) toFamily(rule RecordingRule, results []sample) *dto.MetricFamily {
+	if len(results) == 0 {
+		return nil
+	}
+	sort.Slice(results, func(i, j int) bool { return labelsKey(results[i].labels) < labelsKey(results[j].labels) })
+
+	labelNames := make([]string, 0, len(results[0].labels))
+	for name := range results[0].labels {
+		labelNames = append(labelNames, name)
+	}
+	sort.Strings(labelNames)
+	desc := NewGaugeDesc(rule.Record, rule.Help, labelNames...)
+
+	family := &dto.MetricFamily{
+		Name: &rule.Record,
+		Help: &rule.Help,
+		Type: dto.MetricType_GAUGE.Enum(),
+	}
+	for _, result := range results {
+		values := make([]string, 0, len(labelNames))
+		for _, name := range labelNames {
+			if value, ok := result.labels[name]; ok {
+				values = append(values, value)
+			}
+		}
+		if len(values) != len(labelNames) || len(result.labels) != len(labelNames) {
+			e.logger.Debugw("Recording rule result has inconsistent labels, skipping", "record", rule.Record)
+			continue
+		}
+
+		metric := &dto.Metric{}
+		if err := desc.MustNewConstMetric(result.value*rule.Scale, values...).Write(metric); err != nil {
+			e.logger.Errorw("Failed to write recording rule result", "record", rule.Record, "error", err)
+			continue
+		}
+		family.Metric = append(family.Metric, metric)
+	}
+	return family
+}
+
+func metricValue(metricType dto.MetricType, metric *dto.Metric) (float64, bool) {
+	switch metricType {
+	case dto.MetricType_GAUGE:
+		return metric.GetGauge().GetValue(), true
+	case dto.MetricType_COUNTER:
+		return metric.GetCounter().GetValue(), true
+	case dto.MetricType_UNTYPED:
+		return metric.GetUntyped().GetValue(), true
+	}
+	return 0, false
+}
+
+func labelsKey(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for name, value := range labels {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+const testRecordingRules = `
+rules:
+  - record: solana_node_slots_per_second
+    type: rate
+    metric: solana_node_slot_height
+    window: 1m
+  - record: solana_node_ledger_retention_hours
+    help: Hours of history held in the node's ledger
+    type: difference
+    left: solana_node_slot_height
+    right: solana_node_minimum_ledger_slot
+    scale: 0.00011111111111111112 # 400ms slots to hours
+  - record: solana_node_slot_height_avg
+    type: avg_over_time
+    metric: solana_node_slot_height
+    window: 15s
+  - record: solana_node_slot_share
+    type: ratio
+    left: solana_node_slot_height
+    right: solana_total_slots
+`
+
+func TestLoadRecordingRules(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(testRecordingRules), 0o644))
+
+	rules, err := LoadRecordingRules(path)
+	assert.NoError(t, err)
+	if assert.Len(t, rules, 4) {
+		assert.Equal(t, time.Minute, rules[0].Window)
+		assert.Equal(t, 1.0, rules[0].Scale)
+		assert.Equal(t, "Recording rule: rate(solana_node_slot_height[1m0s])", rules[0].Help)
+		assert.Equal(t, "Hours of history held in the node's ledger", rules[1].Help)
+	}
+
+	invalid := map[string]string{
+		"unknown type":   "rules:\n  - record: a\n    type: sum\n    metric: b\n",
+		"missing window": "rules:\n  - record: a\n    type: rate\n    metric: b\n",
+		"missing right":  "rules:\n  - record: a\n    type: ratio\n    left: b\n",
+		"duplicate":      "rules:\n  - {record: a, type: ratio, left: b, right: c}\n  - {record: a, type: ratio, left: b, right: c}\n",
+	}
+	for name, content := range invalid {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "invalid.yaml")
+			assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+			_, err := LoadRecordingRules(path)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRuleEvaluator(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(testRecordingRules), 0o644))
+	rules, err := LoadRecordingRules(path)
+	assert.NoError(t, err)
+
+	registry := prometheus.NewRegistry()
+	slotHeight := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "solana_node_slot_height", Help: "h"}, []string{NetworkLabel})
+	minimumSlot := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "solana_node_minimum_ledger_slot", Help: "h"}, []string{NetworkLabel})
+	totalSlots := prometheus.NewGauge(prometheus.GaugeOpts{Name: "solana_total_slots", Help: "h"})
+	registry.MustRegister(slotHeight, minimumSlot, totalSlots)
+	evaluator := NewRuleEvaluator(registry, rules)
+
+	gatherAt := func(now time.Time) map[string]float64 {
+		families, err := registry.Gather()
+		assert.NoError(t, err)
+		results := make(map[string]float64)
+		for _, family := range evaluator.evaluate(families, now) {
+			assert.Equal(t, dto.MetricType_GAUGE, family.GetType())
+			if assert.Len(t, family.GetMetric(), 1) {
+				metric := family.GetMetric()[0]
+				assert.Equal(t, "mainnet-beta", metric.GetLabel()[0].GetValue())
+				results[family.GetName()] = metric.GetGauge().GetValue()
+			}
+		}
+		return results
+	}
+
+	start := time.Now()
+	slotHeight.WithLabelValues("mainnet-beta").Set(1000)
+	minimumSlot.WithLabelValues("mainnet-beta").Set(1000 - 9000*3)
+	totalSlots.Set(2000)
+	results := gatherAt(start)
+	// a rate needs two samples
+	assert.NotContains(t, results, "solana_node_slots_per_second")
+	assert.InDelta(t, 3.0, results["solana_node_ledger_retention_hours"], 1e-9)
+	assert.Equal(t, 0.5, results["solana_node_slot_share"])
+
+	slotHeight.WithLabelValues("mainnet-beta").Set(1025)
+	results = gatherAt(start.Add(10 * time.Second))
+	assert.Equal(t, 2.5, results["solana_node_slots_per_second"])
+	assert.Equal(t, 1012.5, results["solana_node_slot_height_avg"])
+
+	// the first sample falls out of the average's window, not the rate's
+	slotHeight.WithLabelValues("mainnet-beta").Set(1050)
+	results = gatherAt(start.Add(20 * time.Second))
+	assert.Equal(t, 2.5, results["solana_node_slots_per_second"])
+	assert.Equal(t, 1037.5, results["solana_node_slot_height_avg"])
+
+	// the full Gather includes both the source and the derived metrics, sorted
+	families, err := evaluator.Gather()
+	assert.NoError(t, err)
+	var names []string
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+	assert.IsIncreasing(t, names)
+	assert.Contains(t, names, "solana_node_ledger_retention_hours")
+	assert.Contains(t, names, "solana_node_slot_height")
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/naviat/solana-rpc-exporter/pkg/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	CheckLabel = "check"
+
+	// AuditCheckTLS passes if the endpoint is served over TLS
+	AuditCheckTLS = "tls"
+	// AuditCheckLegacyTLS passes if TLS 1.0 and 1.1 handshakes are refused
+	AuditCheckLegacyTLS = "tls_legacy_versions"
+	// AuditCheckCertificate passes if the certificate verifies for the endpoint's hostname and
+	// stays valid for longer than auditCertificateMargin
+	AuditCheckCertificate = "certificate"
+	// AuditCheckCORS passes if browsers on other origins are not allowed to call the endpoint
+	AuditCheckCORS = "cors"
+	// AuditCheckProgramAccounts passes if getProgramAccounts without filters is refused
+	AuditCheckProgramAccounts = "unfiltered_program_accounts"
+	// AuditCheckTransactionHistory passes if transaction history queries are refused
+	AuditCheckTransactionHistory = "transaction_history"
+	// AuditCheckAirdrop passes if requestAirdrop is refused; only checked on mainnet-beta
+	AuditCheckAirdrop = "request_airdrop"
+	// AuditCheckWebSocket passes if the WebSocket endpoint refuses an upgrade
+	AuditCheckWebSocket = "websocket_port"
+
+	// AuditInterval is how often every audited endpoint is checked
+	AuditInterval = 10 * time.Minute
+	// auditTimeout bounds each probe
+	auditTimeout = 10 * time.Second
+	// auditCertificateMargin is how long before expiry a certificate fails its check
+	auditCertificateMargin = 14 * 24 * time.Hour
+	// auditOrigin is sent as the Origin of the CORS probe
+	auditOrigin = "https://solana-exporter-audit.invalid"
+	// VoteProgramID owns a modest number of accounts, so an unfiltered scan of it is affordable
+	VoteProgramID = "Vote111111111111111111111111111111111111111"
+)
+
+type (
+	// AuditResult holds the outcome of the checks that apply to an endpoint
+	AuditResult struct {
+		Checks map[string]bool
+		// CertificateExpiry is zero for endpoints without TLS
+		CertificateExpiry time.Time
+	}
+
+	// securityProbe is a JSON-RPC call that an endpoint passes by refusing
+	securityProbe struct {
+		check  string
+		method string
+		params []any
+	}
+
+	// SecurityAuditWatcher checks what internet-facing RPC endpoints expose to anyone who can
+	// reach them: expensive or sensitive methods that answer, permissive CORS, weak TLS and an
+	// open WebSocket endpoint. A check passes when the surface is not exposed.
+	SecurityAuditWatcher struct {
+		logger     *zap.SugaredLogger
+		config     *ExporterConfig
+		httpClient *http.Client
+		// roots verifies endpoint certificates, nil for the system roots
+		roots *x509.CertPool
+		// websocketURL derives the URL WebSocket upgrades of an endpoint are attempted on
+		websocketURL func(endpoint *url.URL) *url.URL
+
+		mu      sync.RWMutex
+		results map[string]*AuditResult
+
+		CheckPassed       *GaugeDesc
+		CertificateExpiry *GaugeDesc
+	}
+)
+
+func NewSecurityAuditWatcher(config *ExporterConfig) *SecurityAuditWatcher {
+	return &SecurityAuditWatcher{
+		logger:       slog.Get(),
+		config:       config,
+		httpClient:   newAuditHTTPClient(nil),
+		websocketURL: websocketURL,
+		results:      make(map[string]*AuditResult),
+
+		CheckPassed: NewGaugeDesc(
+			"solana_rpc_audit_check_passed",
+			"1 if the endpoint passed the security check (the surface is not exposed), 0 if it failed",
+			NetworkLabel, EndpointLabel, CheckLabel,
+		),
+		CertificateExpiry: NewGaugeDesc(
+			"solana_rpc_audit_certificate_expiry_timestamp_seconds",
+			"Unix time at which the endpoint's TLS certificate expires",
+			NetworkLabel, EndpointLabel,
+		),
+	}
+}
+
+// newAuditHTTPClient returns the client probes are sent with, verifying certificates against roots
+func newAuditHTTPClient(roots *x509.CertPool) *http.Client {
+	return &http.Client{
+		Timeout: auditTimeout,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: roots},
+			DisableKeepAlives: true,
+		},
+	}
+}
+
+// websocketURL follows the validator's default of serving WebSockets on the RPC port plus one.
+// Endpoints without an explicit port are usually behind a proxy serving both on the same URL.
+func websocketURL(endpoint *url.URL) *url.URL {
+	websocket := *endpoint
+	websocket.Fragment = ""
+	if port := endpoint.Port(); port != "" {
+		if number, err := strconv.Atoi(port); err == nil {
+			websocket.Host = net.JoinHostPort(endpoint.Hostname(), strconv.Itoa(number+1))
+		}
+	}
+	return &websocket
+}
+
+func (w *SecurityAuditWatcher) WatchEndpoints(ctx context.Context) error {
+	ticker := time.NewTicker(AuditInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, endpoint := range w.config.AuditEndpoints {
+			result, err := w.audit(ctx, endpoint)
+			if err != nil {
+				w.logger.Errorw("Failed to audit endpoint", "endpoint", rpc.EndpointLabel(endpoint), "error", err)
+				w.forget(endpoint)
+				continue
+			}
+			w.update(endpoint, result)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// audit runs every check that applies to the endpoint
+func (w *SecurityAuditWatcher) audit(ctx context.Context, endpoint string) (*AuditResult, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint URL")
+	}
+	// plain HTTP fails the TLS check, which an HTTPS endpoint overwrites below
+	result := &AuditResult{Checks: map[string]bool{AuditCheckTLS: false}}
+	if parsed.Scheme == "https" {
+		// the handshake is inspected on its own connection, so a certificate that fails verification
+		// is still reported without any request being sent to the server presenting it
+		state, err := w.dialTLS(ctx, parsed, tls.VersionTLS10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("TLS handshake failed: %w", err)
+		}
+		result.Checks[AuditCheckTLS] = state.Version >= tls.VersionTLS12
+		result.Checks[AuditCheckLegacyTLS] = !w.acceptsLegacyTLS(ctx, parsed)
+		verifyErr := w.verifyCertificate(state, parsed.Hostname())
+		if len(state.PeerCertificates) > 0 {
+			result.CertificateExpiry = state.PeerCertificates[0].NotAfter
+		}
+		result.Checks[AuditCheckCertificate] = verifyErr == nil && time.Until(result.CertificateExpiry) > auditCertificateMargin
+
+		if verifyErr != nil || !result.Checks[AuditCheckTLS] {
+			// the probes carry the endpoint's path and query, where providers put API keys
+			w.logger.Debugw("Skipping security probes of untrusted endpoint", "endpoint", rpc.EndpointLabel(endpoint), "error", verifyErr)
+			return result, nil
+		}
+	}
+
+	response, err := w.call(ctx, endpoint, "getHealth", []any{}, http.Header{"Origin": {auditOrigin}})
+	if err != nil {
+		return nil, err
+	}
+	if response.status == http.StatusOK {
+		allowed := response.header.Get("Access-Control-Allow-Origin")
+		result.Checks[AuditCheckCORS] = allowed != "*" && allowed != auditOrigin
+	}
+
+	probes := []securityProbe{
+		// only the pubkeys are requested, the check is whether the scan is allowed at all
+		{AuditCheckProgramAccounts, "getProgramAccounts", []any{
+			VoteProgramID, map[string]any{"encoding": "base64", "dataSlice": map[string]int{"offset": 0, "length": 0}},
+		}},
+		{AuditCheckTransactionHistory, "getSignaturesForAddress", []any{VoteProgramID, map[string]int{"limit": 1}}},
+	}
+	if w.config.NetworkName == "mainnet-beta" {
+		probes = append(probes, securityProbe{AuditCheckAirdrop, "requestAirdrop", []any{"11111111111111111111111111111111", 1}})
+	}
+	for _, probe := range probes {
+		response, err := w.call(ctx, endpoint, probe.method, probe.params, nil)
+		if err != nil {
+			// inconclusive, e.g. a timeout; the check is left out rather than guessed
+			w.logger.Debugw("Security probe failed", "endpoint", rpc.EndpointLabel(endpoint), "check", probe.check, "error", err)
+			continue
+		}
+		if response.status != http.StatusOK {
+			// rate limits, auth walls and server errors say nothing about whether the method is served
+			w.logger.Debugw("Security probe was not answered", "endpoint", rpc.EndpointLabel(endpoint), "check", probe.check, "status", response.status)
+			continue
+		}
+		result.Checks[probe.check] = !response.answered
+	}
+
+	result.Checks[AuditCheckWebSocket] = !w.acceptsWebSocket(ctx, w.websocketURL(parsed))
+	return result, nil
+}
+
+// acceptsWebSocket is whether the endpoint answers a WebSocket upgrade with 101 Switching Protocols.
+// A bare connection is not enough, as proxies serve HTTP and WebSockets on the same port.
+func (w *SecurityAuditWatcher) acceptsWebSocket(ctx context.Context, endpoint *url.URL) bool {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return false
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return false
+	}
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Sec-WebSocket-Version", "13")
+	request.Header.Set("Sec-WebSocket-Key", base64.StdEncoding.EncodeToString(key))
+
+	response, err := w.httpClient.Do(request)
+	if err != nil {
+		return false
+	}
+	_ = response.Body.Close()
+	return response.StatusCode == http.StatusSwitchingProtocols
+}
+
+type auditResponse struct {
+	status int
+	// answered is whether the method returned a non-null result rather than an error
+	answered bool
+	header   http.Header
+}
+
+// call sends one JSON-RPC request. Only the start of the response is read, so a probe that
+// answers with a large result costs the endpoint more than it costs the exporter.
+func (w *SecurityAuditWatcher) call(
+	ctx context.Context, endpoint, method string, params []any, header http.Header,
+) (*auditResponse, error) {
+	body, err := json.Marshal(rpc.Request{Jsonrpc: "2.0", Id: 1, Method: method, Params: params})
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for name, values := range header {
+		request.Header[name] = values
+	}
+
+	response, err := w.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("%s probe failed: %w", method, err)
+	}
+	defer response.Body.Close()
+
+	result := &auditResponse{status: response.StatusCode, header: response.Header}
+	if response.StatusCode == http.StatusOK {
+		result.answered = hasResult(response.Body)
+	}
+	return result, nil
+}
+
+// hasResult walks the top-level keys of a JSON-RPC response up to a non-null result or an error
+func hasResult(body io.Reader) bool {
+	decoder := json.NewDecoder(body)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return false
+	}
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		switch key {
+		case "result":
+			token, err := decoder.Token()
+			return err == nil && token != nil
+		case "error":
+			return false
+		default:
+			var skipped json.RawMessage
+			if err := decoder.Decode(&skipped); err != nil {
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// acceptsLegacyTLS is whether the endpoint completes a TLS 1.0 or 1.1 handshake
+func (w *SecurityAuditWatcher) acceptsLegacyTLS(ctx context.Context, endpoint *url.URL) bool {
+	_, err := w.dialTLS(ctx, endpoint, tls.VersionTLS10, tls.VersionTLS11)
+	return err == nil
+}
+
+// dialTLS completes a handshake within the given versions and closes the connection without sending
+// anything. The certificate is not verified, so that an invalid one can be reported by verifyCertificate.
+func (w *SecurityAuditWatcher) dialTLS(
+	ctx context.Context, endpoint *url.URL, minVersion, maxVersion uint16,
+) (tls.ConnectionState, error) {
+	port := endpoint.Port()
+	if port == "" {
+		port = "443"
+	}
+	dialer := tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: auditTimeout},
+		Config: &tls.Config{
+			InsecureSkipVerify: true,
+			ServerName:         endpoint.Hostname(),
+			MinVersion:         minVersion,
+			MaxVersion:         maxVersion,
+		},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(endpoint.Hostname(), port))
+	if err != nil {
+		return tls.ConnectionState{}, err
+	}
+	defer conn.Close()
+	return conn.(*tls.Conn).ConnectionState(), nil
+}
+
+// verifyCertificate verifies the certificate chain presented in state for hostname
+func (w *SecurityAuditWatcher) verifyCertificate(state tls.ConnectionState, hostname string) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("no certificate presented")
+	}
+	intermediates := x509.NewCertPool()
+	for _, certificate := range state.PeerCertificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         w.roots,
+		DNSName:       hostname,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+// update stores the result of an endpoint, logging checks that started failing
+func (w *SecurityAuditWatcher) update(endpoint string, result *AuditResult) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	previous := w.results[endpoint]
+	for check, passed := range result.Checks {
+		if !passed && (previous == nil || previous.Checks[check]) {
+			w.logger.Warnw("Endpoint failed security check", "endpoint", rpc.EndpointLabel(endpoint), "check", check)
+		}
+	}
+	w.results[endpoint] = result
+}
+
+// forget drops the result of an endpoint whose audit failed, rather than exporting a stale one
+func (w *SecurityAuditWatcher) forget(endpoint string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.results, endpoint)
+}
+
+func (w *SecurityAuditWatcher) Describe(ch chan<- *prometheus.Desc) {
+	ch <- w.CheckPassed.Desc
+	ch <- w.CertificateExpiry.Desc
+}
+
+func (w *SecurityAuditWatcher) Collect(ch chan<- prometheus.Metric) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	for endpoint, result := range w.results {
+		label := rpc.EndpointLabel(endpoint)
+		for check, passed := range result.Checks {
+			value := 0.0
+			if passed {
+				value = 1
+			}
+			ch <- w.CheckPassed.MustNewConstMetric(value, w.config.NetworkName, label, check)
+		}
+		if !result.CertificateExpiry.IsZero() {
+			ch <- w.CertificateExpiry.MustNewConstMetric(float64(result.CertificateExpiry.Unix()), w.config.NetworkName, label)
+		}
+	}
+}
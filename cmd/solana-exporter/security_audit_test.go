@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// newStandInRPC answers getHealth and, if open, every other method; otherwise other methods are refused.
+// Like a node, it refuses anything but POST, WebSocket upgrades included.
+func newStandInRPC(t *testing.T, open bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Used HTTP Method is not allowed. POST or OPTIONS is required", http.StatusMethodNotAllowed)
+			return
+		}
+		var request rpc.Request
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Header().Set("Content-Type", "application/json")
+		if open {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+		if request.Method == "getHealth" || open {
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","result":["4Nd1mBQtrMJVYVfKf2PJy9NZUZdTAsp7D4xWLs4gDB4T"],"id":1}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":1}`))
+	})
+}
+
+// newStandInWebSocket completes every WebSocket upgrade and closes the connection straight away
+func newStandInWebSocket(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" {
+			http.Error(w, "expected a WebSocket upgrade", http.StatusBadRequest)
+			return
+		}
+		conn, buffered, err := w.(http.Hijacker).Hijack()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		_, _ = buffered.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		_ = buffered.Flush()
+	}))
+}
+
+// trustTestCertificate makes watcher trust the certificate of server, as httptest certificates are self-signed
+func trustTestCertificate(watcher *SecurityAuditWatcher, server *httptest.Server) {
+	watcher.roots = x509.NewCertPool()
+	watcher.roots.AddCert(server.Certificate())
+	watcher.httpClient = newAuditHTTPClient(watcher.roots)
+}
+
+// closedAddress returns an address nothing listens on
+func closedAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	address := listener.Addr().String()
+	assert.NoError(t, listener.Close())
+	return address
+}
+
+func TestHasResult(t *testing.T) {
+	assert.True(t, hasResult(strings.NewReader(`{"jsonrpc":"2.0","result":[{"pubkey":"a"}`)))
+	assert.True(t, hasResult(strings.NewReader(`{"jsonrpc":"2.0","id":1,"result":"ok"}`)))
+	assert.False(t, hasResult(strings.NewReader(`{"jsonrpc":"2.0","result":null,"id":1}`)))
+	assert.False(t, hasResult(strings.NewReader(`{"jsonrpc":"2.0","error":{"code":-32601},"id":1}`)))
+	assert.False(t, hasResult(strings.NewReader(`Too many requests`)))
+}
+
+func TestWebsocketURL(t *testing.T) {
+	for endpoint, expected := range map[string]string{
+		"http://10.0.0.1:8899":            "http://10.0.0.1:8900",
+		"https://rpc.example.com":         "https://rpc.example.com",
+		"http://rpc.example.com/path?k=v": "http://rpc.example.com/path?k=v",
+	} {
+		parsed, err := url.Parse(endpoint)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, websocketURL(parsed).String())
+	}
+}
+
+func TestSecurityAuditWatcher(t *testing.T) {
+	lockedDown := httptest.NewUnstartedServer(newStandInRPC(t, false))
+	// the refused legacy handshakes would be logged
+	lockedDown.Config.ErrorLog = log.New(io.Discard, "", 0)
+	lockedDown.StartTLS()
+	defer lockedDown.Close()
+
+	open := httptest.NewServer(newStandInRPC(t, true))
+	defer open.Close()
+	websocket := newStandInWebSocket(t)
+	defer websocket.Close()
+
+	legacy := httptest.NewUnstartedServer(newStandInRPC(t, false))
+	legacy.TLS = &tls.Config{MinVersion: tls.VersionTLS10}
+	legacy.StartTLS()
+	defer legacy.Close()
+
+	config := &ExporterConfig{NetworkName: "mainnet-beta"}
+	watcher := NewSecurityAuditWatcher(config)
+	// httptest servers share one certificate
+	trustTestCertificate(watcher, lockedDown)
+	watcher.websocketURL = func(endpoint *url.URL) *url.URL {
+		if endpoint.Host == strings.TrimPrefix(open.URL, "http://") {
+			parsed, _ := url.Parse(websocket.URL)
+			return parsed
+		}
+		return &url.URL{Scheme: "http", Host: closedAddress(t)}
+	}
+	ctx := context.Background()
+
+	result, err := watcher.audit(ctx, lockedDown.URL)
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]bool{
+			AuditCheckTLS:                true,
+			AuditCheckLegacyTLS:          true,
+			AuditCheckCertificate:        true,
+			AuditCheckCORS:               true,
+			AuditCheckProgramAccounts:    true,
+			AuditCheckTransactionHistory: true,
+			AuditCheckAirdrop:            true,
+			AuditCheckWebSocket:          true,
+		}, result.Checks)
+		assert.Equal(t, lockedDown.Certificate().NotAfter, result.CertificateExpiry)
+		watcher.update(lockedDown.URL, result)
+	}
+
+	result, err = watcher.audit(ctx, open.URL)
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]bool{
+			AuditCheckTLS:                false,
+			AuditCheckCORS:               false,
+			AuditCheckProgramAccounts:    false,
+			AuditCheckTransactionHistory: false,
+			AuditCheckAirdrop:            false,
+			AuditCheckWebSocket:          false,
+		}, result.Checks)
+		assert.True(t, result.CertificateExpiry.IsZero())
+		watcher.update(open.URL, result)
+	}
+
+	result, err = watcher.audit(ctx, legacy.URL)
+	if assert.NoError(t, err) {
+		assert.False(t, result.Checks[AuditCheckLegacyTLS])
+		assert.True(t, result.Checks[AuditCheckTLS])
+		assert.True(t, result.Checks[AuditCheckCertificate])
+	}
+
+	// airdrops are expected outside mainnet-beta
+	config.NetworkName = "devnet"
+	result, err = watcher.audit(ctx, open.URL)
+	if assert.NoError(t, err) {
+		assert.NotContains(t, result.Checks, AuditCheckAirdrop)
+	}
+
+	_, err = watcher.audit(ctx, "http://"+closedAddress(t))
+	assert.Error(t, err)
+
+	// 8 checks of the TLS endpoint with its certificate expiry, 6 of the plain HTTP one
+	assert.Equal(t, 15, testutil.CollectAndCount(watcher))
+	assert.Equal(t, 1, testutil.CollectAndCount(watcher, "solana_rpc_audit_certificate_expiry_timestamp_seconds"))
+}
+
+func TestSecurityAuditWatcher_WebSocketWithoutPort(t *testing.T) {
+	// endpoints without an explicit port are upgraded on their own URL, where a proxy serves both
+	watcher := NewSecurityAuditWatcher(&ExporterConfig{NetworkName: "devnet"})
+	watcher.websocketURL = func(endpoint *url.URL) *url.URL { return endpoint }
+	ctx := context.Background()
+
+	// the connection is accepted, but the upgrade is not
+	rpcOnly := httptest.NewServer(newStandInRPC(t, false))
+	defer rpcOnly.Close()
+	result, err := watcher.audit(ctx, rpcOnly.URL)
+	if assert.NoError(t, err) {
+		assert.True(t, result.Checks[AuditCheckWebSocket])
+	}
+
+	websocket := newStandInWebSocket(t)
+	defer websocket.Close()
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			websocket.Config.Handler.ServeHTTP(w, r)
+			return
+		}
+		newStandInRPC(t, false).ServeHTTP(w, r)
+	}))
+	defer proxy.Close()
+	result, err = watcher.audit(ctx, proxy.URL)
+	if assert.NoError(t, err) {
+		assert.False(t, result.Checks[AuditCheckWebSocket])
+	}
+}
+
+func TestSecurityAuditWatcher_UntrustedCertificate(t *testing.T) {
+	probed := false
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probed = true
+		newStandInRPC(t, true).ServeHTTP(w, r)
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	// the self-signed certificate is not in the system roots
+	watcher := NewSecurityAuditWatcher(&ExporterConfig{NetworkName: "mainnet-beta"})
+	result, err := watcher.audit(context.Background(), server.URL)
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]bool{
+			AuditCheckTLS:         true,
+			AuditCheckLegacyTLS:   true,
+			AuditCheckCertificate: false,
+		}, result.Checks)
+		assert.Equal(t, server.Certificate().NotAfter, result.CertificateExpiry)
+	}
+	assert.False(t, probed, "no request may be sent to an endpoint with an untrusted certificate")
+
+	// trusted, but not issued for the hostname
+	trustTestCertificate(watcher, server)
+	result, err = watcher.audit(context.Background(), strings.Replace(server.URL, "127.0.0.1", "localhost", 1))
+	if assert.NoError(t, err) {
+		assert.False(t, result.Checks[AuditCheckCertificate])
+	}
+	assert.False(t, probed)
+}
+
+func TestSecurityAuditWatcher_NotAnswered(t *testing.T) {
+	// a rate limited endpoint neither passes nor fails the checks it did not answer
+	limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+	}))
+	defer limited.Close()
+
+	watcher := NewSecurityAuditWatcher(&ExporterConfig{NetworkName: "mainnet-beta"})
+	watcher.websocketURL = func(endpoint *url.URL) *url.URL { return endpoint }
+	result, err := watcher.audit(context.Background(), limited.URL)
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]bool{AuditCheckTLS: false, AuditCheckWebSocket: true}, result.Checks)
+	}
+}
+
+func TestSecurityAuditWatcher_FailedAudit(t *testing.T) {
+	config := &ExporterConfig{NetworkName: "mainnet-beta", AuditEndpoints: []string{"http://" + closedAddress(t)}}
+	watcher := NewSecurityAuditWatcher(config)
+	watcher.update(config.AuditEndpoints[0], &AuditResult{Checks: map[string]bool{AuditCheckTLS: false}})
+	assert.Equal(t, 1, testutil.CollectAndCount(watcher))
+
+	// the stale result is dropped rather than exported as if it were current
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, watcher.WatchEndpoints(ctx), context.Canceled)
+	assert.Equal(t, 0, testutil.CollectAndCount(watcher))
+}
@@ -42,7 +42,7 @@ func (w *SlotWatcher) WatchSlots(ctx context.Context) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
-			epochInfo, err := w.client.GetEpochInfo(ctx, rpc.CommitmentConfirmed)
+			epochInfo, err := w.client.GetEpochInfo(rpc.WithPriority(ctx, rpc.PriorityCritical), rpc.CommitmentConfirmed)
 			if err != nil {
 				continue
 			}
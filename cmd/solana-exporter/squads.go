@@ -0,0 +1,396 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/naviat/solana-rpc-exporter/pkg/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	MultisigLabel         = "multisig"
+	TransactionIndexLabel = "transaction_index"
+
+	// SquadsProgramID is the Squads v4 multisig program
+	SquadsProgramID = "SQDS4ep65T869zMMBKyuUq6aD6EgTu8psMjkvj52pCf"
+	// SquadsPollInterval is how often multisigs and their proposals are fetched
+	SquadsPollInterval = time.Minute
+
+	// offset of the multisig pubkey in proposal accounts, after the discriminator
+	proposalMultisigOffset = 8
+)
+
+// Squads v4 proposal statuses, in the order of the on-chain enum
+var proposalStatuses = []string{"draft", "active", "rejected", "approved", "executing", "executed", "cancelled"}
+
+var (
+	multisigDiscriminator = anchorDiscriminator("Multisig")
+	proposalDiscriminator = anchorDiscriminator("Proposal")
+)
+
+type (
+	// SquadsMultisig is the part of a Squads v4 multisig account the exporter uses
+	SquadsMultisig struct {
+		Threshold             uint16
+		TimeLock              uint32
+		TransactionIndex      uint64
+		StaleTransactionIndex uint64
+		Members               int
+	}
+
+	// SquadsProposal is the part of a Squads v4 proposal account the exporter uses
+	SquadsProposal struct {
+		TransactionIndex uint64
+		Status           string
+		// StatusTime is when the proposal entered its status; zero for "executing", which has none
+		StatusTime time.Time
+		Approved   int
+		Rejected   int
+		Cancelled  int
+	}
+
+	multisigState struct {
+		multisig  *SquadsMultisig
+		proposals []*SquadsProposal
+	}
+
+	// SquadsWatcher follows the pending proposals of configured Squads v4 multisigs, so that
+	// signers are reminded of proposals waiting for them and unexpected ones stand out.
+	SquadsWatcher struct {
+		client *rpc.Client
+		logger *zap.SugaredLogger
+		config *ExporterConfig
+
+		mu        sync.RWMutex
+		multisigs map[string]*multisigState
+		// seen holds the pending proposals already reported, by multisig and transaction index
+		seen map[string]map[uint64]bool
+
+		MultisigThreshold *GaugeDesc
+		MultisigMembers   *GaugeDesc
+		PendingProposals  *GaugeDesc
+		StaleProposals    *GaugeDesc
+		ProposalApprovals *GaugeDesc
+		ProposalAge       *GaugeDesc
+	}
+)
+
+// anchorDiscriminator is the 8-byte prefix Anchor gives accounts of the named type
+func anchorDiscriminator(account string) []byte {
+	hash := sha256.Sum256([]byte("account:" + account))
+	return hash[:8]
+}
+
+// borshReader reads little-endian Borsh fields, remembering the first error
+type borshReader struct {
+	data []byte
+	err  error
+}
+
+func (r *borshReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.data) < n {
+		r.err = errors.New("account data too short")
+		return nil
+	}
+	field := r.data[:n]
+	r.data = r.data[n:]
+	return field
+}
+
+func (r *borshReader) u8() uint8 {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *borshReader) u16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *borshReader) u32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *borshReader) u64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+// vec skips a vector of fixed-size elements and returns its length
+func (r *borshReader) vec(elementSize int) int {
+	length := int(r.u32())
+	r.next(length * elementSize)
+	return length
+}
+
+func checkDiscriminator(data, discriminator []byte) error {
+	if len(data) < len(discriminator) || string(data[:len(discriminator)]) != string(discriminator) {
+		return errors.New("unexpected account discriminator")
+	}
+	return nil
+}
+
+// DecodeSquadsMultisig decodes a Squads v4 multisig account
+func DecodeSquadsMultisig(data []byte) (*SquadsMultisig, error) {
+	if err := checkDiscriminator(data, multisigDiscriminator); err != nil {
+		return nil, err
+	}
+	r := &borshReader{data: data[len(multisigDiscriminator):]}
+	r.next(32) // create_key
+	r.next(32) // config_authority
+	multisig := &SquadsMultisig{
+		Threshold:             r.u16(),
+		TimeLock:              r.u32(),
+		TransactionIndex:      r.u64(),
+		StaleTransactionIndex: r.u64(),
+	}
+	if r.u8() == 1 {
+		r.next(32) // rent_collector
+	}
+	r.u8()                       // bump
+	multisig.Members = r.vec(33) // pubkey and permission mask
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode multisig: %w", r.err)
+	}
+	return multisig, nil
+}
+
+// DecodeSquadsProposal decodes a Squads v4 proposal account
+func DecodeSquadsProposal(data []byte) (*SquadsProposal, error) {
+	if err := checkDiscriminator(data, proposalDiscriminator); err != nil {
+		return nil, err
+	}
+	r := &borshReader{data: data[len(proposalDiscriminator):]}
+	r.next(32) // multisig
+	proposal := &SquadsProposal{TransactionIndex: r.u64()}
+
+	status := int(r.u8())
+	if r.err == nil && status >= len(proposalStatuses) {
+		return nil, fmt.Errorf("unknown proposal status %d", status)
+	}
+	if r.err == nil {
+		proposal.Status = proposalStatuses[status]
+	}
+	if proposal.Status != "executing" {
+		proposal.StatusTime = time.Unix(int64(r.u64()), 0)
+	}
+	r.u8() // bump
+	proposal.Approved = r.vec(32)
+	proposal.Rejected = r.vec(32)
+	proposal.Cancelled = r.vec(32)
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode proposal: %w", r.err)
+	}
+	return proposal, nil
+}
+
+// IsPending is whether the proposal still awaits votes or execution
+func (p *SquadsProposal) IsPending() bool {
+	switch p.Status {
+	case "draft", "active", "approved", "executing":
+		return true
+	}
+	return false
+}
+
+// IsStale is whether Squads considers the proposal stale: a config change happened after it was
+// created, so it can no longer be approved.
+func (p *SquadsProposal) IsStale(multisig *SquadsMultisig) bool {
+	return (p.Status == "draft" || p.Status == "active") && p.TransactionIndex <= multisig.StaleTransactionIndex
+}
+
+func NewSquadsWatcher(client *rpc.Client, config *ExporterConfig) *SquadsWatcher {
+	return &SquadsWatcher{
+		client:    client,
+		logger:    slog.Get(),
+		config:    config,
+		multisigs: make(map[string]*multisigState),
+		seen:      make(map[string]map[uint64]bool),
+
+		MultisigThreshold: NewGaugeDesc(
+			"solana_squads_multisig_threshold",
+			"Number of approvals the multisig requires to execute a proposal",
+			NetworkLabel, MultisigLabel,
+		),
+		MultisigMembers: NewGaugeDesc(
+			"solana_squads_multisig_members",
+			"Number of members of the multisig",
+			NetworkLabel, MultisigLabel,
+		),
+		PendingProposals: NewGaugeDesc(
+			"solana_squads_pending_proposals",
+			"Number of proposals in draft, active, approved or executing status",
+			NetworkLabel, MultisigLabel,
+		),
+		StaleProposals: NewGaugeDesc(
+			"solana_squads_stale_proposals",
+			"Number of draft or active proposals made stale by a later config change",
+			NetworkLabel, MultisigLabel,
+		),
+		ProposalApprovals: NewGaugeDesc(
+			"solana_squads_proposal_approvals",
+			"Number of approvals of a pending proposal (compare with solana_squads_multisig_threshold)",
+			NetworkLabel, MultisigLabel, TransactionIndexLabel, StatusLabel,
+		),
+		ProposalAge: NewGaugeDesc(
+			"solana_squads_proposal_age_seconds",
+			"Seconds since a pending proposal entered its current status",
+			NetworkLabel, MultisigLabel, TransactionIndexLabel, StatusLabel,
+		),
+	}
+}
+
+func (w *SquadsWatcher) WatchMultisigs(ctx context.Context) error {
+	ticker := time.NewTicker(SquadsPollInterval)
+	defer ticker.Stop()
+
+	bulkCtx := rpc.WithPriority(ctx, rpc.PriorityBulk)
+	for {
+		for _, address := range w.config.SquadsMultisigs {
+			if err := w.checkMultisig(bulkCtx, address); err != nil {
+				w.logger.Errorw("Failed to check Squads multisig", "multisig", address, "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *SquadsWatcher) checkMultisig(ctx context.Context, address string) error {
+	account, err := w.client.GetAccountInfo(ctx, rpc.CommitmentConfirmed, address)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		return errors.New("multisig account not found")
+	}
+	if account.Owner != SquadsProgramID {
+		return fmt.Errorf("account is owned by %s, not the Squads v4 program", account.Owner)
+	}
+	multisig, err := DecodeSquadsMultisig(account.Data)
+	if err != nil {
+		return err
+	}
+
+	accounts, err := w.client.GetProgramAccounts(ctx, rpc.CommitmentConfirmed, SquadsProgramID, []rpc.ProgramAccountFilter{
+		{Memcmp: &rpc.MemcmpFilter{
+			Offset:   0,
+			Bytes:    base64.StdEncoding.EncodeToString(proposalDiscriminator),
+			Encoding: "base64",
+		}},
+		{Memcmp: &rpc.MemcmpFilter{Offset: proposalMultisigOffset, Bytes: address}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list proposals: %w", err)
+	}
+	proposals := make([]*SquadsProposal, 0, len(accounts))
+	for _, account := range accounts {
+		proposal, err := DecodeSquadsProposal(account.Account.Data)
+		if err != nil {
+			w.logger.Warnw("Skipping undecodable Squads proposal", "proposal", account.Pubkey, "error", err)
+			continue
+		}
+		proposals = append(proposals, proposal)
+	}
+	w.update(address, multisig, proposals)
+	return nil
+}
+
+// update replaces the state of a multisig, logging proposals that became pending
+func (w *SquadsWatcher) update(address string, multisig *SquadsMultisig, proposals []*SquadsProposal) {
+	sort.Slice(proposals, func(i, j int) bool { return proposals[i].TransactionIndex < proposals[j].TransactionIndex })
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, known := w.multisigs[address]
+	seen := make(map[uint64]bool)
+	for _, proposal := range proposals {
+		if !proposal.IsPending() {
+			continue
+		}
+		seen[proposal.TransactionIndex] = true
+		// proposals pending at startup are not news
+		if known && !w.seen[address][proposal.TransactionIndex] {
+			w.logger.Warnw("New Squads proposal",
+				"multisig", address,
+				"transaction_index", proposal.TransactionIndex,
+				"status", proposal.Status,
+				"approvals", proposal.Approved,
+				"threshold", multisig.Threshold,
+			)
+		}
+	}
+	w.seen[address] = seen
+	w.multisigs[address] = &multisigState{multisig: multisig, proposals: proposals}
+}
+
+func (w *SquadsWatcher) Describe(ch chan<- *prometheus.Desc) {
+	ch <- w.MultisigThreshold.Desc
+	ch <- w.MultisigMembers.Desc
+	ch <- w.PendingProposals.Desc
+	ch <- w.StaleProposals.Desc
+	ch <- w.ProposalApprovals.Desc
+	ch <- w.ProposalAge.Desc
+}
+
+func (w *SquadsWatcher) Collect(ch chan<- prometheus.Metric) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	now := time.Now()
+	for address, state := range w.multisigs {
+		ch <- w.MultisigThreshold.MustNewConstMetric(float64(state.multisig.Threshold), w.config.NetworkName, address)
+		ch <- w.MultisigMembers.MustNewConstMetric(float64(state.multisig.Members), w.config.NetworkName, address)
+
+		pending, stale := 0, 0
+		for _, proposal := range state.proposals {
+			if !proposal.IsPending() {
+				continue
+			}
+			pending++
+			if proposal.IsStale(state.multisig) {
+				stale++
+			}
+
+			index := strconv.FormatUint(proposal.TransactionIndex, 10)
+			ch <- w.ProposalApprovals.MustNewConstMetric(
+				float64(proposal.Approved), w.config.NetworkName, address, index, proposal.Status,
+			)
+			if !proposal.StatusTime.IsZero() {
+				ch <- w.ProposalAge.MustNewConstMetric(
+					now.Sub(proposal.StatusTime).Seconds(), w.config.NetworkName, address, index, proposal.Status,
+				)
+			}
+		}
+		ch <- w.PendingProposals.MustNewConstMetric(float64(pending), w.config.NetworkName, address)
+		ch <- w.StaleProposals.MustNewConstMetric(float64(stale), w.config.NetworkName, address)
+	}
+}
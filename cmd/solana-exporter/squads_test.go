@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// encodeMultisig builds a Squads v4 multisig account with a rent collector set
+func encodeMultisig(threshold uint16, transactionIndex, staleIndex uint64, members int) []byte {
+	data := append([]byte{}, multisigDiscriminator...)
+	data = append(data, make([]byte, 64)...) // create_key, config_authority
+	data = binary.LittleEndian.AppendUint16(data, threshold)
+	data = binary.LittleEndian.AppendUint32(data, 0) // time_lock
+	data = binary.LittleEndian.AppendUint64(data, transactionIndex)
+	data = binary.LittleEndian.AppendUint64(data, staleIndex)
+	data = append(data, 1)
+	data = append(data, make([]byte, 32)...) // rent_collector
+	data = append(data, 255)                 // bump
+	data = binary.LittleEndian.AppendUint32(data, uint32(members))
+	return append(data, make([]byte, 33*members)...)
+}
+
+func encodeProposal(transactionIndex uint64, status int, statusTime time.Time, approved, rejected int) []byte {
+	data := append([]byte{}, proposalDiscriminator...)
+	data = append(data, make([]byte, 32)...) // multisig
+	data = binary.LittleEndian.AppendUint64(data, transactionIndex)
+	data = append(data, byte(status))
+	if proposalStatuses[status] != "executing" {
+		data = binary.LittleEndian.AppendUint64(data, uint64(statusTime.Unix()))
+	}
+	data = append(data, 254) // bump
+	for _, votes := range []int{approved, rejected, 0} {
+		data = binary.LittleEndian.AppendUint32(data, uint32(votes))
+		data = append(data, make([]byte, 32*votes)...)
+	}
+	return data
+}
+
+func TestDecodeSquadsMultisig(t *testing.T) {
+	multisig, err := DecodeSquadsMultisig(encodeMultisig(3, 12, 9, 5))
+	assert.NoError(t, err)
+	assert.Equal(t, &SquadsMultisig{Threshold: 3, TransactionIndex: 12, StaleTransactionIndex: 9, Members: 5}, multisig)
+
+	_, err = DecodeSquadsMultisig(encodeProposal(1, 1, time.Now(), 0, 0))
+	assert.Error(t, err)
+	_, err = DecodeSquadsMultisig(encodeMultisig(3, 12, 9, 5)[:100])
+	assert.Error(t, err)
+}
+
+func TestDecodeSquadsProposal(t *testing.T) {
+	created := time.Unix(1_700_000_000, 0)
+	proposal, err := DecodeSquadsProposal(encodeProposal(7, 1, created, 2, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, &SquadsProposal{TransactionIndex: 7, Status: "active", StatusTime: created, Approved: 2, Rejected: 1}, proposal)
+	assert.True(t, proposal.IsPending())
+
+	proposal, err = DecodeSquadsProposal(encodeProposal(8, 4, created, 3, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, "executing", proposal.Status)
+	assert.True(t, proposal.StatusTime.IsZero())
+
+	unknown := encodeProposal(9, 1, created, 0, 0)
+	unknown[48] = 42
+	_, err = DecodeSquadsProposal(unknown)
+	assert.Error(t, err)
+}
+
+func TestSquadsWatcher(t *testing.T) {
+	now := time.Now()
+	programAccount := func(pubkey string, data []byte) map[string]any {
+		return map[string]any{
+			"pubkey":  pubkey,
+			"account": map[string]any{"data": []string{base64.StdEncoding.EncodeToString(data), "base64"}, "owner": SquadsProgramID},
+		}
+	}
+	_, client := rpc.NewMockClient(t, map[string]any{
+		"getAccountInfo": map[string]any{
+			"context": map[string]int{"slot": 100},
+			"value": map[string]any{
+				"data":  []string{base64.StdEncoding.EncodeToString(encodeMultisig(2, 12, 10, 3)), "base64"},
+				"owner": SquadsProgramID,
+			},
+		},
+		"getProgramAccounts": []map[string]any{
+			programAccount("proposal9", encodeProposal(9, 1, now.Add(-48*time.Hour), 1, 0)),
+			programAccount("proposal11", encodeProposal(11, 1, now.Add(-time.Hour), 0, 0)),
+			programAccount("proposal12", encodeProposal(12, 3, now.Add(-time.Minute), 2, 0)),
+			programAccount("proposal5", encodeProposal(5, 5, now.Add(-72*time.Hour), 2, 0)),
+			programAccount("garbage", []byte{1, 2, 3}),
+		},
+	})
+	config := &ExporterConfig{NetworkName: "mainnet-beta", SquadsMultisigs: []string{"multisigA"}}
+	watcher := NewSquadsWatcher(client, config)
+
+	assert.NoError(t, watcher.checkMultisig(context.Background(), "multisigA"))
+	state := watcher.multisigs["multisigA"]
+	if !assert.NotNil(t, state) {
+		return
+	}
+	assert.Len(t, state.proposals, 4)
+	assert.Equal(t, map[uint64]bool{9: true, 11: true, 12: true}, watcher.seen["multisigA"])
+
+	tests := []collectionTest{
+		watcher.MultisigThreshold.makeCollectionTest(NewLV(2, "multisigA", "mainnet-beta")),
+		watcher.MultisigMembers.makeCollectionTest(NewLV(3, "multisigA", "mainnet-beta")),
+		watcher.PendingProposals.makeCollectionTest(NewLV(3, "multisigA", "mainnet-beta")),
+		// only proposal 9 predates the stale transaction index and is still open
+		watcher.StaleProposals.makeCollectionTest(NewLV(1, "multisigA", "mainnet-beta")),
+		watcher.ProposalApprovals.makeCollectionTest(
+			NewLV(1, "multisigA", "mainnet-beta", "active", "9"),
+			NewLV(0, "multisigA", "mainnet-beta", "active", "11"),
+			NewLV(2, "multisigA", "mainnet-beta", "approved", "12"),
+		),
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := testutil.CollectAndCompare(watcher, strings.NewReader(test.ExpectedResponse), test.Name)
+			assert.NoError(t, err)
+		})
+	}
+	assert.Equal(t, 3, testutil.CollectAndCount(watcher, "solana_squads_proposal_age_seconds"))
+
+	t.Run("wrong owner", func(t *testing.T) {
+		_, client := rpc.NewMockClient(t, map[string]any{
+			"getAccountInfo": map[string]any{
+				"context": map[string]int{"slot": 100},
+				"value":   map[string]any{"data": []string{"", "base64"}, "owner": "11111111111111111111111111111111"},
+			},
+		})
+		assert.Error(t, NewSquadsWatcher(client, config).checkMultisig(context.Background(), "multisigA"))
+	})
+}
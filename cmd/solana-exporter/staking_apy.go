@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/naviat/solana-rpc-exporter/pkg/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	VoteAccountLabel  = "vote_account"
+	StakeAccountLabel = "stake_account"
+	// StakeProgramID is the native stake program
+	StakeProgramID = "Stake11111111111111111111111111111111111111"
+	// StakingApyInterval is how often the reference stake accounts and new epochs' rewards are fetched
+	StakingApyInterval = time.Hour
+	// stakeVoterOffset is the offset of the vote account in a delegated stake account,
+	// after the state tag and the meta (rent reserve, authorities and lockup)
+	stakeVoterOffset = 124
+	// stakeStateDelegated is the state tag of a stake account with a delegation
+	stakeStateDelegated = 2
+	secondsPerYear      = 365.25 * 24 * 60 * 60
+)
+
+type (
+	// epochRewards holds the inflation rewards of the reference stake accounts for one epoch
+	epochRewards struct {
+		// by stake account, in configured order; nil where the account earned nothing
+		rewards []*rpc.InflationReward
+		// blockTime of the slot the rewards were credited at, zero if unknown
+		blockTime int64
+	}
+
+	// StakingYield is the yield estimated from one reference stake account's rewards
+	StakingYield struct {
+		VoteAccount string
+		// Apy is the compounded annual yield over the sampled epochs
+		Apy float64
+		// LastEpochYield is the yield of the most recent epoch alone
+		LastEpochYield float64
+		Epochs         int
+		// Commission of the most recently rewarded epoch, nil if unknown
+		Commission *int
+	}
+
+	// StakingApyWatcher estimates the staking yield of validators from the inflation rewards of
+	// stake accounts delegated to them. The rewards are what a delegator actually received, so the
+	// estimate already accounts for commission, vote credits missed and inflation changes.
+	StakingApyWatcher struct {
+		client *rpc.Client
+		logger *zap.SugaredLogger
+		config *ExporterConfig
+
+		// only touched by the watching goroutine
+		epochs map[int64]*epochRewards
+
+		mu     sync.RWMutex
+		yields map[string]*StakingYield
+
+		Apy            *GaugeDesc
+		LastEpochYield *GaugeDesc
+		Commission     *GaugeDesc
+		SampledEpochs  *GaugeDesc
+	}
+)
+
+// DecodeStakeVoter returns the vote account a stake account is delegated to
+func DecodeStakeVoter(data []byte) (string, error) {
+	if len(data) < stakeVoterOffset+32 {
+		return "", errors.New("account data too short")
+	}
+	if state := binary.LittleEndian.Uint32(data); state != stakeStateDelegated {
+		return "", fmt.Errorf("stake account is not delegated (state %d)", state)
+	}
+	return encodeBase58(data[stakeVoterOffset : stakeVoterOffset+32]), nil
+}
+
+// estimateYield compounds the per-epoch yields of a stake account's rewards, given oldest first.
+// Epochs before the first reward are skipped, as the stake was not active yet; missing rewards
+// after it count as a zero yield. It returns nil if there is no reward at all.
+func estimateYield(rewards []*rpc.InflationReward, epochDuration time.Duration) *StakingYield {
+	var (
+		yield  StakingYield
+		growth = 1.0
+	)
+	for _, reward := range rewards {
+		if reward == nil {
+			if yield.Epochs > 0 {
+				yield.Epochs++
+				yield.LastEpochYield = 0
+			}
+			continue
+		}
+		epochYield := 0.0
+		if principal := reward.PostBalance - reward.Amount; principal > 0 {
+			epochYield = float64(reward.Amount) / float64(principal)
+		}
+		growth *= 1 + epochYield
+		yield.Epochs++
+		yield.LastEpochYield = epochYield
+		yield.Commission = reward.Commission
+	}
+	if yield.Epochs == 0 || epochDuration <= 0 {
+		return nil
+	}
+
+	epochsPerYear := secondsPerYear / epochDuration.Seconds()
+	yield.Apy = math.Pow(growth, epochsPerYear/float64(yield.Epochs)) - 1
+	return &yield
+}
+
+func NewStakingApyWatcher(client *rpc.Client, config *ExporterConfig) *StakingApyWatcher {
+	return &StakingApyWatcher{
+		client: client,
+		logger: slog.Get(),
+		config: config,
+		epochs: make(map[int64]*epochRewards),
+		yields: make(map[string]*StakingYield),
+
+		Apy: NewGaugeDesc(
+			"solana_staking_apy",
+			"Annualized staking yield of the validator, compounded from the reference stake account's "+
+				"inflation rewards over the sampled epochs",
+			NetworkLabel, VoteAccountLabel, StakeAccountLabel,
+		),
+		LastEpochYield: NewGaugeDesc(
+			"solana_staking_last_epoch_yield",
+			"Yield of the reference stake account in the most recent completed epoch, not annualized",
+			NetworkLabel, VoteAccountLabel, StakeAccountLabel,
+		),
+		Commission: NewGaugeDesc(
+			"solana_staking_reward_commission_percent",
+			"Vote account commission applied to the most recent inflation reward",
+			NetworkLabel, VoteAccountLabel, StakeAccountLabel,
+		),
+		SampledEpochs: NewGaugeDesc(
+			"solana_staking_apy_epochs",
+			"Number of epochs the staking yield was estimated over",
+			NetworkLabel, VoteAccountLabel, StakeAccountLabel,
+		),
+	}
+}
+
+func (w *StakingApyWatcher) WatchStakingApy(ctx context.Context) error {
+	ticker := time.NewTicker(StakingApyInterval)
+	defer ticker.Stop()
+
+	bulkCtx := rpc.WithPriority(ctx, rpc.PriorityBulk)
+	for {
+		if err := w.update(bulkCtx); err != nil {
+			w.logger.Errorw("Failed to estimate staking yield", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// update fetches the rewards of epochs not seen yet and re-estimates the yields
+func (w *StakingApyWatcher) update(ctx context.Context) error {
+	epochInfo, err := w.client.GetEpochInfo(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return fmt.Errorf("failed to get epoch info: %w", err)
+	}
+	// rewards for an epoch are paid at the start of the next one
+	last := epochInfo.Epoch - 1
+	if last < 0 {
+		return nil
+	}
+	first := max(last-int64(w.config.ApyEpochs)+1, 0)
+
+	for epoch := first; epoch <= last; epoch++ {
+		if _, ok := w.epochs[epoch]; ok {
+			continue
+		}
+		entry, err := w.fetchEpoch(ctx, epoch)
+		if err != nil {
+			return err
+		}
+		w.epochs[epoch] = entry
+	}
+	for epoch := range w.epochs {
+		if epoch < first {
+			delete(w.epochs, epoch)
+		}
+	}
+	epochDuration := w.epochDuration(first, last, epochInfo)
+
+	yields := make(map[string]*StakingYield)
+	for i, address := range w.config.ApyStakeAccounts {
+		voter, err := w.stakeVoter(ctx, address)
+		if err != nil {
+			w.logger.Warnw("Skipping reference stake account", "stake_account", address, "error", err)
+			continue
+		}
+
+		rewards := make([]*rpc.InflationReward, 0, last-first+1)
+		for epoch := first; epoch <= last; epoch++ {
+			var reward *rpc.InflationReward
+			if entry := w.epochs[epoch]; i < len(entry.rewards) {
+				reward = entry.rewards[i]
+			}
+			rewards = append(rewards, reward)
+		}
+		if yield := estimateYield(rewards, epochDuration); yield != nil {
+			yield.VoteAccount = voter
+			yields[address] = yield
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.yields = yields
+	return nil
+}
+
+func (w *StakingApyWatcher) fetchEpoch(ctx context.Context, epoch int64) (*epochRewards, error) {
+	rewards, err := w.client.GetInflationReward(ctx, rpc.CommitmentFinalized, w.config.ApyStakeAccounts, epoch)
+	var rpcErr *rpc.RPCError
+	if errors.As(err, &rpcErr) && len(w.config.ApyStakeAccounts) > 1 {
+		// one invalid account fails the whole batch, so the others are fetched one by one
+		rewards, err = w.fetchEpochPerAccount(ctx, epoch)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inflation rewards of epoch %d: %w", epoch, err)
+	}
+	entry := &epochRewards{rewards: rewards}
+	for _, reward := range rewards {
+		if reward == nil {
+			continue
+		}
+		// all rewards of an epoch are credited in the same slot
+		blockTime, err := w.client.GetBlockTime(ctx, reward.EffectiveSlot)
+		if err != nil {
+			w.logger.Debugw("Failed to get block time of reward slot", "slot", reward.EffectiveSlot, "error", err)
+		} else {
+			entry.blockTime = blockTime
+		}
+		break
+	}
+	return entry, nil
+}
+
+// fetchEpochPerAccount fetches the reward of each stake account on its own, leaving the accounts
+// that fail without a reward; it only fails if all of them do
+func (w *StakingApyWatcher) fetchEpochPerAccount(ctx context.Context, epoch int64) ([]*rpc.InflationReward, error) {
+	rewards := make([]*rpc.InflationReward, len(w.config.ApyStakeAccounts))
+	var lastErr error
+	failed := 0
+	for i, address := range w.config.ApyStakeAccounts {
+		reward, err := w.client.GetInflationReward(ctx, rpc.CommitmentFinalized, []string{address}, epoch)
+		if err != nil {
+			w.logger.Warnw("Skipping reference stake account rewards", "stake_account", address, "epoch", epoch, "error", err)
+			lastErr = err
+			failed++
+			continue
+		}
+		if len(reward) > 0 {
+			rewards[i] = reward[0]
+		}
+	}
+	if failed == len(rewards) {
+		return nil, lastErr
+	}
+	return rewards, nil
+}
+
+// epochDuration averages the time between the reward slots of the sampled epochs, falling back to
+// the target slot time if fewer than two of them have a block time
+func (w *StakingApyWatcher) epochDuration(first, last int64, epochInfo *rpc.EpochInfo) time.Duration {
+	var earliest, latest int64 = -1, -1
+	for epoch := first; epoch <= last; epoch++ {
+		if w.epochs[epoch].blockTime == 0 {
+			continue
+		}
+		if earliest < 0 {
+			earliest = epoch
+		}
+		latest = epoch
+	}
+	if earliest >= 0 && latest > earliest {
+		elapsed := w.epochs[latest].blockTime - w.epochs[earliest].blockTime
+		if elapsed > 0 {
+			return time.Duration(elapsed) * time.Second / time.Duration(latest-earliest)
+		}
+	}
+	return time.Duration(epochInfo.SlotsInEpoch) * targetSlotTime
+}
+
+func (w *StakingApyWatcher) stakeVoter(ctx context.Context, address string) (string, error) {
+	account, err := w.client.GetAccountInfo(ctx, rpc.CommitmentFinalized, address)
+	if err != nil {
+		return "", err
+	}
+	if account == nil {
+		return "", errors.New("stake account not found")
+	}
+	if account.Owner != StakeProgramID {
+		return "", fmt.Errorf("account is owned by %s, not the stake program", account.Owner)
+	}
+	return DecodeStakeVoter(account.Data)
+}
+
+func (w *StakingApyWatcher) Describe(ch chan<- *prometheus.Desc) {
+	ch <- w.Apy.Desc
+	ch <- w.LastEpochYield.Desc
+	ch <- w.Commission.Desc
+	ch <- w.SampledEpochs.Desc
+}
+
+func (w *StakingApyWatcher) Collect(ch chan<- prometheus.Metric) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	for address, yield := range w.yields {
+		ch <- w.Apy.MustNewConstMetric(yield.Apy, w.config.NetworkName, yield.VoteAccount, address)
+		ch <- w.LastEpochYield.MustNewConstMetric(yield.LastEpochYield, w.config.NetworkName, yield.VoteAccount, address)
+		ch <- w.SampledEpochs.MustNewConstMetric(float64(yield.Epochs), w.config.NetworkName, yield.VoteAccount, address)
+		if yield.Commission != nil {
+			ch <- w.Commission.MustNewConstMetric(float64(*yield.Commission), w.config.NetworkName, yield.VoteAccount, address)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// encodeStakeAccount builds a stake account in the given state, delegated to voter
+func encodeStakeAccount(state uint32, voter []byte) []byte {
+	data := binary.LittleEndian.AppendUint32(nil, state)
+	data = append(data, make([]byte, stakeVoterOffset-4)...) // meta
+	data = append(data, voter...)
+	return append(data, make([]byte, 200-len(data))...)
+}
+
+func TestDecodeStakeVoter(t *testing.T) {
+	voter := bytes.Repeat([]byte{7}, 32)
+	address, err := DecodeStakeVoter(encodeStakeAccount(stakeStateDelegated, voter))
+	assert.NoError(t, err)
+	assert.Equal(t, encodeBase58(voter), address)
+
+	// initialized but not delegated
+	_, err = DecodeStakeVoter(encodeStakeAccount(1, voter))
+	assert.Error(t, err)
+	_, err = DecodeStakeVoter(encodeStakeAccount(stakeStateDelegated, voter)[:100])
+	assert.Error(t, err)
+}
+
+func TestEstimateYield(t *testing.T) {
+	commission := 5
+	reward := func(amount, postBalance int64) *rpc.InflationReward {
+		return &rpc.InflationReward{Amount: amount, PostBalance: postBalance, Commission: &commission}
+	}
+	twoDays := 48 * time.Hour
+	epochsPerYear := secondsPerYear / twoDays.Seconds()
+
+	// epochs before the stake earned anything are not counted
+	yield := estimateYield([]*rpc.InflationReward{nil, reward(1_000, 1_001_000), reward(1_001, 1_002_001)}, twoDays)
+	if assert.NotNil(t, yield) {
+		assert.Equal(t, 2, yield.Epochs)
+		assert.InDelta(t, 0.001, yield.LastEpochYield, 1e-12)
+		assert.InDelta(t, math.Pow(1.001, epochsPerYear)-1, yield.Apy, 1e-9)
+		assert.Equal(t, 5, *yield.Commission)
+	}
+
+	// a missed reward afterwards counts as an epoch without yield
+	yield = estimateYield([]*rpc.InflationReward{reward(1_000, 1_001_000), nil}, twoDays)
+	if assert.NotNil(t, yield) {
+		assert.Equal(t, 2, yield.Epochs)
+		assert.Equal(t, 0.0, yield.LastEpochYield)
+		assert.InDelta(t, math.Pow(1.001, epochsPerYear/2)-1, yield.Apy, 1e-9)
+	}
+
+	assert.Nil(t, estimateYield([]*rpc.InflationReward{nil, nil}, twoDays))
+}
+
+func TestStakingApyWatcher(t *testing.T) {
+	voter := bytes.Repeat([]byte{7}, 32)
+	server, client := rpc.NewMockClient(t, map[string]any{
+		"getEpochInfo": map[string]int{"epoch": 100, "slotsInEpoch": 432_000, "absoluteSlot": 43_200_100},
+		"getAccountInfo": map[string]any{
+			"context": map[string]int{"slot": 43_200_100},
+			"value": map[string]any{
+				"data":     []string{base64.StdEncoding.EncodeToString(encodeStakeAccount(stakeStateDelegated, voter)), "base64"},
+				"owner":    StakeProgramID,
+				"lamports": 1_002_001,
+			},
+		},
+	})
+	// rewards of epochs 97 to 99 are paid at the first slot of the following epoch, two days apart
+	start := time.Unix(1_700_000_000, 0)
+	for i, epoch := range []int64{97, 98, 99} {
+		effectiveSlot := (epoch + 1) * 432_000
+		server.SetOpt(rpc.InflationRewardOpt, epoch, []any{map[string]any{
+			"epoch": epoch, "effectiveSlot": effectiveSlot, "amount": 1_000, "postBalance": 1_001_000, "commission": 7,
+		}})
+		server.SetOpt(rpc.BlockTimeOpt, effectiveSlot, start.Add(time.Duration(i)*48*time.Hour).Unix())
+	}
+
+	config := &ExporterConfig{NetworkName: "mainnet-beta", ApyStakeAccounts: []string{"stakeA"}, ApyEpochs: 3}
+	watcher := NewStakingApyWatcher(client, config)
+	assert.NoError(t, watcher.update(context.Background()))
+	assert.Len(t, watcher.epochs, 3)
+
+	expected := math.Pow(1.001, secondsPerYear/(48*time.Hour).Seconds()) - 1
+	assert.InDelta(t, expected, watcher.yields["stakeA"].Apy, 1e-9)
+
+	// only newly completed epochs are fetched, older ones are dropped
+	server.SetOpt(rpc.EasyResultsOpt, "getEpochInfo", map[string]int{"epoch": 101, "slotsInEpoch": 432_000})
+	assert.NoError(t, watcher.update(context.Background()))
+	assert.Len(t, watcher.epochs, 3)
+	assert.NotContains(t, watcher.epochs, int64(97))
+	// epoch 100 paid no reward to the stake account
+	yield := watcher.yields["stakeA"]
+	assert.Equal(t, 3, yield.Epochs)
+	assert.Equal(t, 0.0, yield.LastEpochYield)
+	assert.Equal(t, encodeBase58(voter), yield.VoteAccount)
+	assert.Equal(t, 7, *yield.Commission)
+
+	assert.Equal(t, 4, testutil.CollectAndCount(watcher))
+	assert.Equal(t, 1, testutil.CollectAndCount(watcher, "solana_staking_apy"))
+}
+
+func TestStakingApyWatcher_InvalidAccount(t *testing.T) {
+	server, client := rpc.NewMockClient(t, map[string]any{
+		"getEpochInfo": map[string]int{"epoch": 100, "slotsInEpoch": 432_000, "absoluteSlot": 43_200_100},
+		"getAccountInfo": map[string]any{
+			"context": map[string]int{"slot": 43_200_100},
+			"value": map[string]any{
+				"data":     []string{base64.StdEncoding.EncodeToString(encodeStakeAccount(stakeStateDelegated, bytes.Repeat([]byte{7}, 32))), "base64"},
+				"owner":    StakeProgramID,
+				"lamports": 1_002_001,
+			},
+		},
+	})
+	// the invalid account fails every batch it is part of
+	server.SetOpt(rpc.InflationRewardOpt, int64(99), map[string]any{
+		"stakeA":  map[string]any{"epoch": 99, "effectiveSlot": 43_200_000, "amount": 1_000, "postBalance": 1_001_000},
+		"invalid": &rpc.RPCError{Code: -32602, Message: "Invalid param: Invalid"},
+	})
+
+	config := &ExporterConfig{NetworkName: "mainnet-beta", ApyStakeAccounts: []string{"stakeA", "invalid"}, ApyEpochs: 1}
+	watcher := NewStakingApyWatcher(client, config)
+	assert.NoError(t, watcher.update(context.Background()))
+	assert.Contains(t, watcher.yields, "stakeA")
+	assert.NotContains(t, watcher.yields, "invalid")
+}
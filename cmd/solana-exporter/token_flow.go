@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/naviat/solana-rpc-exporter/pkg/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	MintLabel = "mint"
+
+	// TokenSampleInterval is how often the newest confirmed block is sampled for token transfers
+	TokenSampleInterval = 5 * time.Second
+)
+
+type (
+	// tokenBalanceChange is the balance of one token account before and after a transaction
+	tokenBalanceChange struct {
+		pre, post uint64
+		decimals  int
+	}
+
+	// TokenFlowWatcher samples the newest confirmed block with full transaction details and counts
+	// the amounts of the configured mints that moved between token accounts, giving on-chain flow
+	// trends without an indexer. Only sampled blocks are counted, so volume is best read relative
+	// to solana_token_sampled_blocks_total.
+	TokenFlowWatcher struct {
+		client *rpc.Client
+		logger *zap.SugaredLogger
+		config *ExporterConfig
+		mints  map[string]bool
+
+		// only touched by the watching goroutine
+		lastSlot int64
+
+		TransferVolume *prometheus.CounterVec
+		Transfers      *prometheus.CounterVec
+		SampledBlocks  *prometheus.CounterVec
+	}
+)
+
+// tokenTransfers returns the amount of each mint in mints that a transaction moved between token
+// accounts, scaled by the mint's decimals. Minted or burned amounts are not counted: an amount moves
+// only as far as it left some accounts and arrived in others.
+func tokenTransfers(meta *rpc.TransactionMeta, mints map[string]bool) map[string]float64 {
+	changes := make(map[string]map[int]*tokenBalanceChange)
+	record := func(balances []rpc.TokenBalance, post bool) {
+		for _, balance := range balances {
+			if !mints[balance.Mint] {
+				continue
+			}
+			amount, err := strconv.ParseUint(balance.UiTokenAmount.Amount, 10, 64)
+			if err != nil {
+				continue
+			}
+			if changes[balance.Mint] == nil {
+				changes[balance.Mint] = make(map[int]*tokenBalanceChange)
+			}
+			change := changes[balance.Mint][balance.AccountIndex]
+			if change == nil {
+				change = &tokenBalanceChange{}
+				changes[balance.Mint][balance.AccountIndex] = change
+			}
+			change.decimals = balance.UiTokenAmount.Decimals
+			if post {
+				change.post = amount
+			} else {
+				change.pre = amount
+			}
+		}
+	}
+	// accounts opened or closed by the transaction only appear on one side, with a zero balance on the other
+	record(meta.PreTokenBalances, false)
+	record(meta.PostTokenBalances, true)
+
+	moved := make(map[string]float64)
+	for mint, accounts := range changes {
+		var received, sent uint64
+		decimals := 0
+		for _, change := range accounts {
+			if change.post > change.pre {
+				received += change.post - change.pre
+			} else {
+				sent += change.pre - change.post
+			}
+			decimals = change.decimals
+		}
+		if amount := min(received, sent); amount > 0 {
+			moved[mint] = float64(amount) / math.Pow10(decimals)
+		}
+	}
+	return moved
+}
+
+func NewTokenFlowWatcher(client *rpc.Client, config *ExporterConfig) *TokenFlowWatcher {
+	watcher := &TokenFlowWatcher{
+		client: client,
+		logger: slog.Get(),
+		config: config,
+		mints:  make(map[string]bool),
+
+		TransferVolume: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "solana_token_transfer_volume_total",
+				Help: "Amount of the mint, scaled by its decimals, moved between token accounts in sampled blocks",
+			},
+			[]string{NetworkLabel, MintLabel},
+		),
+		Transfers: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "solana_token_transfers_total",
+				Help: "Successful transactions in sampled blocks that moved the mint between token accounts",
+			},
+			[]string{NetworkLabel, MintLabel},
+		),
+		SampledBlocks: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "solana_token_sampled_blocks_total",
+				Help: "Blocks sampled for token transfers",
+			},
+			[]string{NetworkLabel},
+		),
+	}
+	for _, mint := range config.TokenMints {
+		watcher.mints[mint] = true
+		// mints without transfers yet are exported as zero rather than missing
+		watcher.TransferVolume.WithLabelValues(config.NetworkName, mint)
+		watcher.Transfers.WithLabelValues(config.NetworkName, mint)
+	}
+	watcher.SampledBlocks.WithLabelValues(config.NetworkName)
+	return watcher
+}
+
+func (w *TokenFlowWatcher) WatchTokenFlows(ctx context.Context) error {
+	ticker := time.NewTicker(TokenSampleInterval)
+	defer ticker.Stop()
+
+	bulkCtx := rpc.WithPriority(ctx, rpc.PriorityBulk)
+	for {
+		if err := w.sample(bulkCtx); err != nil {
+			w.logger.Errorw("Failed to sample block for token transfers", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// sample counts the token transfers of the newest confirmed block, unless it was sampled already
+func (w *TokenFlowWatcher) sample(ctx context.Context) error {
+	slot, err := w.client.GetSlot(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		return fmt.Errorf("failed to get confirmed slot: %w", err)
+	}
+	if slot == w.lastSlot {
+		return nil
+	}
+	w.lastSlot = slot
+
+	block, err := w.client.GetBlock(ctx, rpc.CommitmentConfirmed, slot, "full")
+	if err != nil {
+		if rpc.IsSlotSkipped(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get block %d: %w", slot, err)
+	}
+	w.SampledBlocks.WithLabelValues(w.config.NetworkName).Inc()
+
+	for _, transaction := range block.Transactions {
+		if transaction.Meta == nil || transaction.Meta.Err != nil {
+			continue
+		}
+		for mint, amount := range tokenTransfers(transaction.Meta, w.mints) {
+			w.TransferVolume.WithLabelValues(w.config.NetworkName, mint).Add(amount)
+			w.Transfers.WithLabelValues(w.config.NetworkName, mint).Inc()
+		}
+	}
+	return nil
+}
+
+func (w *TokenFlowWatcher) Describe(ch chan<- *prometheus.Desc) {
+	w.TransferVolume.Describe(ch)
+	w.Transfers.Describe(ch)
+	w.SampledBlocks.Describe(ch)
+}
+
+func (w *TokenFlowWatcher) Collect(ch chan<- prometheus.Metric) {
+	w.TransferVolume.Collect(ch)
+	w.Transfers.Collect(ch)
+	w.SampledBlocks.Collect(ch)
+}
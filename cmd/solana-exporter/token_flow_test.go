@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func tokenBalance(index int, mint, amount string, decimals int) rpc.TokenBalance {
+	return rpc.TokenBalance{AccountIndex: index, Mint: mint, UiTokenAmount: rpc.TokenAmount{Amount: amount, Decimals: decimals}}
+}
+
+func TestTokenTransfers(t *testing.T) {
+	mints := map[string]bool{"mintA": true, "mintB": true}
+	meta := &rpc.TransactionMeta{
+		PreTokenBalances: []rpc.TokenBalance{
+			tokenBalance(1, "mintA", "5000000", 6),
+			tokenBalance(3, "mintB", "100", 2),
+			tokenBalance(4, "mintC", "100", 0),
+		},
+		PostTokenBalances: []rpc.TokenBalance{
+			// 3 mintA to a newly opened account
+			tokenBalance(1, "mintA", "2000000", 6),
+			tokenBalance(2, "mintA", "3000000", 6),
+			// mintB is only minted, which moves nothing
+			tokenBalance(3, "mintB", "250", 2),
+			tokenBalance(4, "mintC", "0", 0),
+			tokenBalance(5, "mintC", "100", 0),
+		},
+	}
+	assert.Equal(t, map[string]float64{"mintA": 3}, tokenTransfers(meta, mints))
+
+	// an account closed after sending its whole balance
+	meta = &rpc.TransactionMeta{
+		PreTokenBalances:  []rpc.TokenBalance{tokenBalance(1, "mintB", "150", 2), tokenBalance(2, "mintB", "0", 2)},
+		PostTokenBalances: []rpc.TokenBalance{tokenBalance(2, "mintB", "150", 2)},
+	}
+	assert.Equal(t, map[string]float64{"mintB": 1.5}, tokenTransfers(meta, mints))
+	assert.Empty(t, tokenTransfers(&rpc.TransactionMeta{}, mints))
+}
+
+func TestTokenFlowWatcher(t *testing.T) {
+	balance := func(index int, amount string) map[string]any {
+		return map[string]any{"accountIndex": index, "mint": "mintA", "uiTokenAmount": map[string]any{"amount": amount, "decimals": 6}}
+	}
+	transaction := func(err any, pre, post string) map[string]any {
+		return map[string]any{"meta": map[string]any{
+			"err":               err,
+			"preTokenBalances":  []any{balance(1, pre), balance(2, "0")},
+			"postTokenBalances": []any{balance(1, post), balance(2, "1000000")},
+		}}
+	}
+	server, client := rpc.NewMockClient(t, map[string]any{"getSlot": 100})
+	server.SetOpt(rpc.BlockOpt, int64(100), map[string]any{
+		"transactions": []any{
+			transaction(nil, "1000000", "0"),
+			transaction(nil, "1000000", "0"),
+			// failed transactions move nothing, whatever their balances say
+			transaction(map[string]any{"InstructionError": []any{0, "Custom"}}, "1000000", "0"),
+			map[string]any{"meta": nil},
+		},
+	})
+	server.SetOpt(rpc.BlockOpt, int64(101), &rpc.RPCError{Code: rpc.SlotSkippedCode, Message: "Slot 101 was skipped"})
+	config := &ExporterConfig{NetworkName: "mainnet-beta", TokenMints: []string{"mintA", "mintB"}}
+	watcher := NewTokenFlowWatcher(client, config)
+	ctx := context.Background()
+
+	assert.NoError(t, watcher.sample(ctx))
+	// the same slot is not sampled twice
+	assert.NoError(t, watcher.sample(ctx))
+	server.SetOpt(rpc.EasyResultsOpt, "getSlot", 101)
+	assert.NoError(t, watcher.sample(ctx))
+	server.SetOpt(rpc.EasyResultsOpt, "getSlot", 102)
+	assert.Error(t, watcher.sample(ctx))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(watcher.TransferVolume.WithLabelValues("mainnet-beta", "mintA")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(watcher.Transfers.WithLabelValues("mainnet-beta", "mintA")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(watcher.Transfers.WithLabelValues("mainnet-beta", "mintB")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(watcher.SampledBlocks.WithLabelValues("mainnet-beta")))
+	assert.Equal(t, 5, testutil.CollectAndCount(watcher))
+}
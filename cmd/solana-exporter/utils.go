@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
 	"github.com/naviat/solana-rpc-exporter/pkg/slog"
+	"strings"
 )
 
 // GetEpochBounds returns the first slot and last slot within an [inclusive] Epoch
@@ -23,6 +24,48 @@ func isValidNetwork(network string) bool {
 	return false
 }
 
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// encodeBase58 encodes raw bytes, such as a pubkey read from account data, the way Solana prints them
+func encodeBase58(data []byte) string {
+	zeros := 0
+	for zeros < len(data) && data[zeros] == 0 {
+		zeros++
+	}
+
+	// base58 digits of the remaining bytes, least significant first
+	var digits []byte
+	for _, b := range data[zeros:] {
+		carry := int(b)
+		for i := range digits {
+			carry += int(digits[i]) << 8
+			digits[i] = byte(carry % 58)
+			carry /= 58
+		}
+		for carry > 0 {
+			digits = append(digits, byte(carry%58))
+			carry /= 58
+		}
+	}
+
+	encoded := []byte(strings.Repeat("1", zeros))
+	for i := len(digits) - 1; i >= 0; i-- {
+		encoded = append(encoded, base58Alphabet[digits[i]])
+	}
+	return string(encoded)
+}
+
 // assertf is a utility function for runtime assertions
 func assertf(condition bool, format string, args ...any) {
 	logger := slog.Get()
@@ -34,6 +34,11 @@ func TestToString(t *testing.T) {
 	assert.Equal(t, "<nil>", toString(nil))
 }
 
+func TestSplitList(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, splitList("a, b,,"))
+	assert.Empty(t, splitList(""))
+}
+
 func TestAssertf(t *testing.T) {
 	// Test that nothing bad happens when condition is true.
 	// (We can’t fully test a failing assertf without causing a fatal exit.)
@@ -41,3 +46,13 @@ func TestAssertf(t *testing.T) {
 		assertf(true, "should not fail")
 	})
 }
+
+func TestEncodeBase58(t *testing.T) {
+	assert.Equal(t, "11111111111111111111111111111111", encodeBase58(make([]byte, 32)))
+	stakeProgram := []byte{
+		0x06, 0xa1, 0xd8, 0x17, 0x91, 0x37, 0x54, 0x2a, 0x98, 0x34, 0x37, 0xbd, 0xfe, 0x2a, 0x7a, 0xb2,
+		0x55, 0x7f, 0x53, 0x5c, 0x8a, 0x78, 0x72, 0x2b, 0x68, 0xa4, 0x9d, 0xc0, 0x00, 0x00, 0x00, 0x00,
+	}
+	assert.Equal(t, "Stake11111111111111111111111111111111111111", encodeBase58(stakeProgram))
+	assert.Equal(t, "", encodeBase58(nil))
+}
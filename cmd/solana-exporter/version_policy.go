@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/naviat/solana-rpc-exporter/pkg/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ReasonLabel   = "reason"
+	RequiredLabel = "required"
+
+	// VersionCompliant means the version meets every requirement in effect and upcoming
+	VersionCompliant = "compliant"
+	// VersionUpgradeDue means a minimum version the node does not meet takes effect in the future
+	VersionUpgradeDue = "upgrade_due"
+	// VersionNoncompliant means the node is below a minimum version that is in effect
+	VersionNoncompliant = "noncompliant"
+	// VersionBlocked means the version is explicitly blocked
+	VersionBlocked = "blocked"
+	// VersionUnknown means the node's version could not be fetched or parsed
+	VersionUnknown = "unknown"
+	// versionUnavailableReason is the reason label when getVersion fails; the error itself is only logged,
+	// as transport errors carry the full RPC URL and would create a series per distinct message
+	versionUnavailableReason = "version unavailable"
+)
+
+type (
+	// MinimumVersion requires Version or later from Effective on
+	MinimumVersion struct {
+		Version   string    `yaml:"version"`
+		Effective time.Time `yaml:"effective"`
+		Reason    string    `yaml:"reason"`
+	}
+
+	// BlockedVersion forbids running Version
+	BlockedVersion struct {
+		Version string `yaml:"version"`
+		Reason  string `yaml:"reason"`
+	}
+
+	// NetworkVersionPolicy is the version policy of one network
+	NetworkVersionPolicy struct {
+		Minimum []MinimumVersion `yaml:"minimum"`
+		Blocked []BlockedVersion `yaml:"blocked"`
+	}
+
+	// VersionPolicy holds the policy of each network, by network name
+	VersionPolicy struct {
+		Networks map[string]NetworkVersionPolicy `yaml:"networks"`
+	}
+
+	// VersionCompliance is the outcome of checking a version against a policy
+	VersionCompliance struct {
+		Status string
+		Reason string
+		// Deadline is the unmet minimum the status is due to, nil if every minimum is met
+		Deadline *MinimumVersion
+	}
+
+	// VersionPolicyCollector checks the RPC node's version against the policy of its network on
+	// every scrape, turning solana_node_version_info into an upgrade tracker.
+	VersionPolicyCollector struct {
+		client *rpc.Client
+		logger *zap.SugaredLogger
+		config *ExporterConfig
+		policy NetworkVersionPolicy
+
+		VersionCompliance *GaugeDesc
+		DaysUntilDeadline *GaugeDesc
+		Compliant         *GaugeDesc
+	}
+)
+
+// LoadVersionPolicy reads and validates a YAML version policy file
+func LoadVersionPolicy(path string) (*VersionPolicy, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read version policy: %w", err)
+	}
+	var policy VersionPolicy
+	if err := yaml.Unmarshal(content, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse version policy %s: %w", path, err)
+	}
+	for network, networkPolicy := range policy.Networks {
+		for _, minimum := range networkPolicy.Minimum {
+			if _, err := parseVersion(minimum.Version); err != nil {
+				return nil, fmt.Errorf("invalid minimum version for %s: %w", network, err)
+			}
+		}
+		for _, blocked := range networkPolicy.Blocked {
+			if _, err := parseVersion(blocked.Version); err != nil {
+				return nil, fmt.Errorf("invalid blocked version for %s: %w", network, err)
+			}
+		}
+	}
+	return &policy, nil
+}
+
+// parseVersion parses major.minor.patch, ignoring any pre-release or build suffix
+func parseVersion(version string) ([3]int, error) {
+	var parsed [3]int
+	core, _, _ := strings.Cut(version, "-")
+	core, _, _ = strings.Cut(core, "+")
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return parsed, fmt.Errorf("version %q is not major.minor.patch", version)
+	}
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return parsed, fmt.Errorf("version %q is not major.minor.patch", version)
+		}
+		parsed[i] = number
+	}
+	return parsed, nil
+}
+
+// compareVersions returns -1, 0 or 1 as a is older than, the same as or newer than b
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// Check evaluates version against the policy at now. Blocked versions take precedence over
+// minimums in effect, which take precedence over upcoming ones.
+func (p NetworkVersionPolicy) Check(version string, now time.Time) VersionCompliance {
+	parsed, err := parseVersion(version)
+	if err != nil {
+		return VersionCompliance{Status: VersionUnknown, Reason: err.Error()}
+	}
+
+	for _, blocked := range p.Blocked {
+		// validated when the policy was loaded
+		blockedVersion, _ := parseVersion(blocked.Version)
+		if compareVersions(parsed, blockedVersion) == 0 {
+			return VersionCompliance{
+				Status: VersionBlocked,
+				Reason: withReason(fmt.Sprintf("%s is blocked", blocked.Version), blocked.Reason),
+			}
+		}
+	}
+
+	// overdue is the highest unmet minimum in effect, upcoming the nearest unmet one not yet in effect
+	var overdue, upcoming *MinimumVersion
+	var overdueVersion [3]int
+	for i := range p.Minimum {
+		minimum := &p.Minimum[i]
+		minimumVersion, _ := parseVersion(minimum.Version)
+		if compareVersions(parsed, minimumVersion) >= 0 {
+			continue
+		}
+		if !minimum.Effective.After(now) {
+			if overdue == nil || compareVersions(minimumVersion, overdueVersion) > 0 {
+				overdue, overdueVersion = minimum, minimumVersion
+			}
+		} else if upcoming == nil || minimum.Effective.Before(upcoming.Effective) {
+			upcoming = minimum
+		}
+	}
+
+	switch {
+	case overdue != nil:
+		summary := fmt.Sprintf("below %s, required since %s", overdue.Version, overdue.Effective.Format(time.DateOnly))
+		return VersionCompliance{Status: VersionNoncompliant, Reason: withReason(summary, overdue.Reason), Deadline: overdue}
+	case upcoming != nil:
+		summary := fmt.Sprintf("%s required from %s", upcoming.Version, upcoming.Effective.Format(time.DateOnly))
+		return VersionCompliance{Status: VersionUpgradeDue, Reason: withReason(summary, upcoming.Reason), Deadline: upcoming}
+	}
+	return VersionCompliance{Status: VersionCompliant}
+}
+
+func withReason(summary, reason string) string {
+	if reason == "" {
+		return summary
+	}
+	return summary + ": " + reason
+}
+
+func NewVersionPolicyCollector(client *rpc.Client, config *ExporterConfig, policy *VersionPolicy) *VersionPolicyCollector {
+	logger := slog.Get()
+	networkPolicy, ok := policy.Networks[config.NetworkName]
+	if !ok {
+		logger.Warnw("Version policy has no entry for the network, every version is compliant", "network", config.NetworkName)
+	}
+	return &VersionPolicyCollector{
+		client: client,
+		logger: logger,
+		config: config,
+		policy: networkPolicy,
+
+		VersionCompliance: NewGaugeDesc(
+			"solana_node_version_compliance",
+			fmt.Sprintf(
+				"Always 1, with the RPC node's version, its policy %s (%s, %s, %s, %s or %s) and the %s",
+				StatusLabel, VersionCompliant, VersionUpgradeDue, VersionNoncompliant, VersionBlocked, VersionUnknown, ReasonLabel,
+			),
+			NetworkLabel, VersionLabel, StatusLabel, ReasonLabel,
+		),
+		DaysUntilDeadline: NewGaugeDesc(
+			"solana_node_version_days_until_deadline",
+			"Days until the unmet minimum version behind the compliance status takes effect, negative once it has",
+			NetworkLabel, VersionLabel, RequiredLabel,
+		),
+		Compliant: NewGaugeDesc(
+			"solana_node_version_compliant",
+			"1 if the RPC node's version is compliant or only has upcoming upgrades due, 0 otherwise",
+			NetworkLabel, VersionLabel,
+		),
+	}
+}
+
+func (c *VersionPolicyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.VersionCompliance.Desc
+	ch <- c.DaysUntilDeadline.Desc
+	ch <- c.Compliant.Desc
+}
+
+func (c *VersionPolicyCollector) Collect(ch chan<- prometheus.Metric) {
+	version, err := c.client.GetVersion(context.Background())
+	if err != nil {
+		c.logger.Errorw("Failed to get version for policy check", "error", err)
+		ch <- c.VersionCompliance.MustNewConstMetric(1, c.config.NetworkName, VersionUnknown, VersionUnknown, versionUnavailableReason)
+		return
+	}
+
+	now := time.Now()
+	compliance := c.policy.Check(version, now)
+	ch <- c.VersionCompliance.MustNewConstMetric(1, c.config.NetworkName, version, compliance.Status, compliance.Reason)
+
+	compliant := 0.0
+	if compliance.Status == VersionCompliant || compliance.Status == VersionUpgradeDue {
+		compliant = 1
+	}
+	ch <- c.Compliant.MustNewConstMetric(compliant, c.config.NetworkName, version)
+
+	if compliance.Deadline != nil {
+		days := compliance.Deadline.Effective.Sub(now).Hours() / 24
+		ch <- c.DaysUntilDeadline.MustNewConstMetric(
+			math.Round(days*100)/100, c.config.NetworkName, version, compliance.Deadline.Version,
+		)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/naviat/solana-rpc-exporter/pkg/rpc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+const testVersionPolicy = `
+networks:
+  mainnet-beta:
+    minimum:
+      - version: 2.0.21
+        effective: 2025-01-15T00:00:00Z
+      - version: 2.1.11
+        effective: 2025-03-01T00:00:00Z
+        reason: SIMD-0207 activation
+    blocked:
+      - version: 2.1.3
+        reason: crash on startup
+  testnet:
+    minimum:
+      - version: 2.2.0
+        effective: 2025-01-01T00:00:00Z
+`
+
+func loadTestVersionPolicy(t *testing.T) *VersionPolicy {
+	path := filepath.Join(t.TempDir(), "versions.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(testVersionPolicy), 0o644))
+	policy, err := LoadVersionPolicy(path)
+	assert.NoError(t, err)
+	return policy
+}
+
+func TestLoadVersionPolicy(t *testing.T) {
+	policy := loadTestVersionPolicy(t)
+	if assert.NotNil(t, policy) {
+		assert.Len(t, policy.Networks, 2)
+		assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), policy.Networks["mainnet-beta"].Minimum[1].Effective)
+	}
+
+	path := filepath.Join(t.TempDir(), "invalid.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("networks:\n  devnet:\n    blocked:\n      - version: two\n"), 0o644))
+	_, err := LoadVersionPolicy(path)
+	assert.Error(t, err)
+}
+
+func TestParseVersion(t *testing.T) {
+	version, err := parseVersion("2.1.13")
+	assert.NoError(t, err)
+	assert.Equal(t, [3]int{2, 1, 13}, version)
+
+	version, err = parseVersion("2.2.0-rc.1+jito")
+	assert.NoError(t, err)
+	assert.Equal(t, [3]int{2, 2, 0}, version)
+
+	for _, invalid := range []string{"", "2.1", "2.x.1", "unknown"} {
+		_, err = parseVersion(invalid)
+		assert.Error(t, err, invalid)
+	}
+	assert.Equal(t, -1, compareVersions([3]int{2, 0, 21}, [3]int{2, 1, 0}))
+	assert.Equal(t, 1, compareVersions([3]int{2, 1, 11}, [3]int{2, 1, 3}))
+}
+
+func TestNetworkVersionPolicy_Check(t *testing.T) {
+	policy := loadTestVersionPolicy(t).Networks["mainnet-beta"]
+	now := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		version  string
+		status   string
+		reason   string
+		deadline string
+	}{
+		{"2.1.11", VersionCompliant, "", ""},
+		{"2.2.0-jito", VersionCompliant, "", ""},
+		{"2.1.3", VersionBlocked, "2.1.3 is blocked: crash on startup", ""},
+		{"2.1.4", VersionUpgradeDue, "2.1.11 required from 2025-03-01: SIMD-0207 activation", "2.1.11"},
+		{"2.0.20", VersionNoncompliant, "below 2.0.21, required since 2025-01-15", "2.0.21"},
+		{"unknown", VersionUnknown, `version "unknown" is not major.minor.patch`, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.version, func(t *testing.T) {
+			compliance := policy.Check(test.version, now)
+			assert.Equal(t, test.status, compliance.Status)
+			assert.Equal(t, test.reason, compliance.Reason)
+			if test.deadline == "" {
+				assert.Nil(t, compliance.Deadline)
+			} else if assert.NotNil(t, compliance.Deadline) {
+				assert.Equal(t, test.deadline, compliance.Deadline.Version)
+			}
+		})
+	}
+
+	// once the second minimum is in effect, the node falls behind the higher one
+	compliance := policy.Check("2.0.20", time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, "2.1.11", compliance.Deadline.Version)
+}
+
+func TestVersionPolicyCollector(t *testing.T) {
+	_, client := rpc.NewMockClient(t, map[string]any{"getVersion": map[string]string{"solana-core": "2.1.20"}})
+	config := &ExporterConfig{NetworkName: "testnet"}
+	collector := NewVersionPolicyCollector(client, config, loadTestVersionPolicy(t))
+
+	tests := []collectionTest{
+		collector.VersionCompliance.makeCollectionTest(
+			NewLV(1, "testnet", "below 2.2.0, required since 2025-01-01", VersionNoncompliant, "2.1.20"),
+		),
+		collector.Compliant.makeCollectionTest(NewLV(0, "testnet", "2.1.20")),
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := testutil.CollectAndCompare(collector, strings.NewReader(test.ExpectedResponse), test.Name)
+			assert.NoError(t, err)
+		})
+	}
+	assert.Equal(t, 3, testutil.CollectAndCount(collector))
+}
+
+func TestVersionPolicyCollector_VersionUnavailable(t *testing.T) {
+	// the transport error would carry the URL, API key included
+	client := rpc.NewRPCClient("http://127.0.0.1:1/?api-key=secret", time.Second)
+	collector := NewVersionPolicyCollector(client, &ExporterConfig{NetworkName: "testnet"}, loadTestVersionPolicy(t))
+
+	test := collector.VersionCompliance.makeCollectionTest(
+		NewLV(1, "testnet", versionUnavailableReason, VersionUnknown, VersionUnknown),
+	)
+	err := testutil.CollectAndCompare(collector, strings.NewReader(test.ExpectedResponse), test.Name)
+	assert.NoError(t, err)
+}
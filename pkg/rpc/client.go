@@ -8,6 +8,9 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -27,6 +30,11 @@ const (
 	CommitmentFinalized Commitment = "finalized"
 	CommitmentConfirmed Commitment = "confirmed"
 	CommitmentProcessed Commitment = "processed"
+
+	// HealthOk, HealthBehind and HealthUnknown are the answers of the native GET /health endpoint
+	HealthOk      = "ok"
+	HealthBehind  = "behind"
+	HealthUnknown = "unknown"
 )
 
 type (
@@ -36,6 +44,12 @@ type (
 		HttpTimeout time.Duration
 		logger      *zap.SugaredLogger
 
+		// Observer, if set, receives per-phase HTTP timings of every request
+		Observer TraceObserver
+
+		// Scheduler, if set, orders requests by the priority attached to their context
+		Scheduler *Scheduler
+
 		// Cache fields
 		cacheMutex    sync.RWMutex
 		versionCache  *cachedValue[string]
@@ -124,6 +138,14 @@ func getResponse[T any](
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	if client.Scheduler != nil {
+		release, err := client.Scheduler.Acquire(ctx, PriorityFromContext(ctx))
+		if err != nil {
+			return fmt.Errorf("%s RPC call failed: %w", method, err)
+		}
+		defer release()
+	}
+
 	if client.logger != nil {
 		client.logger.Debugf("Making RPC request to %s: %s", client.RpcUrl, string(buffer))
 	}
@@ -135,9 +157,19 @@ func getResponse[T any](
 
 	req.Header.Set("Content-Type", "application/json")
 
+	// the trace is reported on every path, as failed requests are the ones worth diagnosing
+	outcome := OutcomeError
+	var bodyDone time.Time
+	if client.Observer != nil {
+		trace := &requestTrace{}
+		req = req.WithContext(httptrace.WithClientTrace(ctx, trace.clientTrace()))
+		defer func() { trace.report(client.Observer, EndpointLabel(client.RpcUrl), outcome, bodyDone) }()
+	}
+
 	start := time.Now()
 	resp, err := client.HttpClient.Do(req)
 	if err != nil {
+		outcome = transportOutcome(err)
 		if client.logger != nil {
 			client.logger.Errorf("RPC request failed: %v", err)
 		}
@@ -155,8 +187,10 @@ func getResponse[T any](
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
+		outcome = transportOutcome(err)
 		return fmt.Errorf("error reading response: %w", err)
 	}
+	bodyDone = time.Now()
 
 	if client.logger != nil {
 		client.logger.Debugf("RPC response: %s", string(body))
@@ -167,13 +201,27 @@ func getResponse[T any](
 	}
 
 	if rpcResponse.Error.Code != 0 {
+		outcome = OutcomeRPCError
 		rpcResponse.Error.Method = method
 		return &rpcResponse.Error
 	}
 
+	outcome = OutcomeSuccess
 	return nil
 }
 
+// Call performs a single RPC request for an arbitrary method and returns the undecoded result
+func (c *Client) Call(ctx context.Context, method string, params []any) (json.RawMessage, error) {
+	if params == nil {
+		params = []any{}
+	}
+	var resp Response[json.RawMessage]
+	if err := getResponse(ctx, c, method, params, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Result, nil
+}
+
 // Core RPC methods
 func (c *Client) GetBlockTime(ctx context.Context, slot int64) (int64, error) {
 	var resp Response[int64]
@@ -252,6 +300,52 @@ func (c *Client) GetHealth(ctx context.Context) (string, error) {
 	return resp.Result, nil
 }
 
+// GetNativeHealth fetches the node's plain-text GET /health endpoint, which load balancers
+// usually check instead of the getHealth method. It is served at the root of the RPC URL's host.
+// Unlike GetHealth, the result is never cached.
+func (c *Client) GetNativeHealth(ctx context.Context) (*HealthStatus, error) {
+	endpoint, err := url.Parse(c.RpcUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RPC URL: %w", err)
+	}
+	endpoint.Path, endpoint.RawQuery = "/health", ""
+
+	if c.Scheduler != nil {
+		release, err := c.Scheduler.Acquire(ctx, PriorityFromContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("health request failed: %w", err)
+		}
+		defer release()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("health request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// the answer is a single word; anything longer is an error page
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+	text := strings.TrimSpace(string(body))
+	status, _, _ := strings.Cut(text, " ")
+	switch status {
+	case HealthOk, HealthBehind, HealthUnknown:
+		health := &HealthStatus{Status: status}
+		if text != status {
+			health.Message = text
+		}
+		return health, nil
+	}
+	return nil, fmt.Errorf("unexpected health response (HTTP %d): %q", resp.StatusCode, text)
+}
+
 func (c *Client) GetMinimumLedgerSlot(ctx context.Context) (int64, error) {
 	var resp Response[int64]
 	if err := getResponse(ctx, c, "minimumLedgerSlot", []any{}, &resp); err != nil {
@@ -267,3 +361,136 @@ func (c *Client) GetFirstAvailableBlock(ctx context.Context) (int64, error) {
 	}
 	return resp.Result, nil
 }
+
+func (c *Client) GetClusterNodes(ctx context.Context) ([]ClusterNode, error) {
+	var resp Response[[]ClusterNode]
+	if err := getResponse(ctx, c, "getClusterNodes", []any{}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Result, nil
+}
+
+func (c *Client) GetVoteAccounts(ctx context.Context, commitment Commitment) (*VoteAccounts, error) {
+	var resp Response[VoteAccounts]
+	config := map[string]string{"commitment": string(commitment)}
+	if err := getResponse(ctx, c, "getVoteAccounts", []any{config}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Result, nil
+}
+
+func (c *Client) GetIdentity(ctx context.Context) (string, error) {
+	var resp Response[struct {
+		Identity string `json:"identity"`
+	}]
+	if err := getResponse(ctx, c, "getIdentity", []any{}, &resp); err != nil {
+		return "", err
+	}
+	return resp.Result.Identity, nil
+}
+
+// GetLeaderSchedule returns the current epoch's leader slots of identity, as indices relative to the
+// first slot of the epoch. The result is empty if identity has no leader slots in the epoch.
+func (c *Client) GetLeaderSchedule(ctx context.Context, commitment Commitment, identity string) ([]int64, error) {
+	var resp Response[map[string][]int64]
+	config := map[string]string{"commitment": string(commitment), "identity": identity}
+	if err := getResponse(ctx, c, "getLeaderSchedule", []any{nil, config}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Result[identity], nil
+}
+
+// GetAccountInfo returns the account at address with its data, or nil if it does not exist
+func (c *Client) GetAccountInfo(ctx context.Context, commitment Commitment, address string) (*AccountInfo, error) {
+	var resp Response[ContextualResult[*AccountInfo]]
+	config := map[string]string{"commitment": string(commitment), "encoding": "base64"}
+	if err := getResponse(ctx, c, "getAccountInfo", []any{address, config}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Result.Value, nil
+}
+
+// GetProgramAccounts returns the accounts owned by program that match all filters
+func (c *Client) GetProgramAccounts(
+	ctx context.Context, commitment Commitment, program string, filters []ProgramAccountFilter,
+) ([]ProgramAccount, error) {
+	var resp Response[[]ProgramAccount]
+	config := map[string]any{"commitment": string(commitment), "encoding": "base64", "filters": filters}
+	if err := getResponse(ctx, c, "getProgramAccounts", []any{program, config}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Result, nil
+}
+
+// GetInflationReward returns the inflation rewards of addresses for epoch, in the same order. An
+// entry is nil if the address received no reward in that epoch.
+func (c *Client) GetInflationReward(
+	ctx context.Context, commitment Commitment, addresses []string, epoch int64,
+) ([]*InflationReward, error) {
+	var resp Response[[]*InflationReward]
+	config := map[string]any{"commitment": string(commitment), "epoch": epoch}
+	if err := getResponse(ctx, c, "getInflationReward", []any{addresses, config}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Result, nil
+}
+
+// GetRecentPrioritizationFees returns the priority fees of the recent slots the node holds, for
+// transactions that write-lock all of accounts (any transaction if accounts is empty)
+func (c *Client) GetRecentPrioritizationFees(ctx context.Context, accounts []string) ([]PrioritizationFee, error) {
+	var resp Response[[]PrioritizationFee]
+	if accounts == nil {
+		accounts = []string{}
+	}
+	if err := getResponse(ctx, c, "getRecentPrioritizationFees", []any{accounts}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Result, nil
+}
+
+// RequestAirdrop asks the node's faucet for lamports sent to address and returns the signature of
+// the airdrop transaction. Only devnet, testnet and local faucets serve it.
+func (c *Client) RequestAirdrop(ctx context.Context, commitment Commitment, address string, lamports int64) (string, error) {
+	var resp Response[string]
+	config := map[string]string{"commitment": string(commitment)}
+	if err := getResponse(ctx, c, "requestAirdrop", []any{address, lamports, config}, &resp); err != nil {
+		return "", err
+	}
+	return resp.Result, nil
+}
+
+// GetSignatureStatuses returns the statuses of signatures, in the same order. An entry is nil if
+// the node does not know the signature (yet).
+func (c *Client) GetSignatureStatuses(ctx context.Context, signatures []string) ([]*SignatureStatus, error) {
+	var resp Response[ContextualResult[[]*SignatureStatus]]
+	if err := getResponse(ctx, c, "getSignatureStatuses", []any{signatures}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Result.Value, nil
+}
+
+func (c *Client) GetSlot(ctx context.Context, commitment Commitment) (int64, error) {
+	var resp Response[int64]
+	config := map[string]string{"commitment": string(commitment)}
+	if err := getResponse(ctx, c, "getSlot", []any{config}, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Result, nil
+}
+
+// GetBlock fetches the block at slot. transactionDetails is one of "full", "accounts", "signatures"
+// or "none"; rewards are not requested.
+func (c *Client) GetBlock(ctx context.Context, commitment Commitment, slot int64, transactionDetails string) (*Block, error) {
+	var resp Response[Block]
+	config := map[string]any{
+		"commitment":                     string(commitment),
+		"encoding":                       "json",
+		"transactionDetails":             transactionDetails,
+		"rewards":                        false,
+		"maxSupportedTransactionVersion": 0,
+	}
+	if err := getResponse(ctx, c, "getBlock", []any{slot, config}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Result, nil
+}
@@ -93,6 +93,193 @@ func TestClient_GetMinimumLedgerSlot(t *testing.T) {
 	assert.Equal(t, int64(250), slot)
 }
 
+func TestClient_Call(t *testing.T) {
+	_, client := newMethodTester(t, "getSlot", int64(166_598))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	result, err := client.Call(ctx, "getSlot", nil)
+	assert.NoError(t, err)
+	assert.JSONEq(t, "166598", string(result))
+
+	_, err = client.Call(ctx, "getBalance", []any{"11111111111111111111111111111111"})
+	var rpcErr *RPCError
+	assert.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, "getBalance", rpcErr.Method)
+}
+
+func TestClient_GetClusterNodes(t *testing.T) {
+	_, client := newMethodTester(t, "getClusterNodes", []map[string]any{
+		{"pubkey": "nodeA", "gossip": "10.0.0.1:8001", "version": "2.0.21", "shredVersion": 50093},
+		{"pubkey": "nodeB", "gossip": nil},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	nodes, err := client.GetClusterNodes(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 2)
+	assert.Equal(t, "10.0.0.1:8001", *nodes[0].Gossip)
+	assert.Equal(t, "2.0.21", *nodes[0].Version)
+	assert.Nil(t, nodes[1].Gossip)
+}
+
+func TestClient_GetVoteAccounts(t *testing.T) {
+	_, client := newMethodTester(t, "getVoteAccounts", map[string]any{
+		"current": []map[string]any{{
+			"votePubkey":     "voteA",
+			"nodePubkey":     "nodeA",
+			"activatedStake": 42,
+			"commission":     5,
+			"epochCredits":   [][3]int64{{600, 1000, 500}},
+		}},
+		"delinquent": []map[string]any{},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	accounts, err := client.GetVoteAccounts(ctx, CommitmentConfirmed)
+	assert.NoError(t, err)
+	assert.Len(t, accounts.Current, 1)
+	assert.Equal(t, int64(42), accounts.Current[0].ActivatedStake)
+	assert.Equal(t, [][3]int64{{600, 1000, 500}}, accounts.Current[0].EpochCredits)
+	assert.Empty(t, accounts.Delinquent)
+}
+
+func TestClient_GetIdentity(t *testing.T) {
+	_, client := newMethodTester(t, "getIdentity", map[string]string{"identity": "nodeA"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	identity, err := client.GetIdentity(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "nodeA", identity)
+}
+
+func TestClient_GetLeaderSchedule(t *testing.T) {
+	_, client := newMethodTester(t, "getLeaderSchedule", map[string]any{"nodeA": []int64{4, 5, 6, 7}})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	slots, err := client.GetLeaderSchedule(ctx, CommitmentFinalized, "nodeA")
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{4, 5, 6, 7}, slots)
+
+	slots, err = client.GetLeaderSchedule(ctx, CommitmentFinalized, "nodeB")
+	assert.NoError(t, err)
+	assert.Empty(t, slots)
+}
+
+func TestClient_GetAccountInfo(t *testing.T) {
+	server, client := newMethodTester(t, "getAccountInfo", map[string]any{
+		"context": map[string]int{"slot": 100},
+		"value": map[string]any{
+			"data":       []string{"AQID", "base64"},
+			"executable": false,
+			"lamports":   1_000_000,
+			"owner":      "11111111111111111111111111111111",
+			"space":      3,
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	account, err := client.GetAccountInfo(ctx, CommitmentConfirmed, "accountA")
+	assert.NoError(t, err)
+	assert.Equal(t, AccountData{1, 2, 3}, account.Data)
+	assert.Equal(t, int64(1_000_000), account.Lamports)
+
+	// missing accounts have a null value
+	server.SetOpt(EasyResultsOpt, "getAccountInfo", map[string]any{"context": map[string]int{"slot": 100}, "value": nil})
+	account, err = client.GetAccountInfo(ctx, CommitmentConfirmed, "accountB")
+	assert.NoError(t, err)
+	assert.Nil(t, account)
+}
+
+func TestClient_GetProgramAccounts(t *testing.T) {
+	_, client := newMethodTester(t, "getProgramAccounts", []map[string]any{{
+		"pubkey":  "accountA",
+		"account": map[string]any{"data": []string{"BAU=", "base64"}, "owner": "programA", "lamports": 10},
+	}})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	accounts, err := client.GetProgramAccounts(ctx, CommitmentConfirmed, "programA", []ProgramAccountFilter{
+		{Memcmp: &MemcmpFilter{Offset: 8, Bytes: "accountB"}},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, accounts, 1)
+	assert.Equal(t, "accountA", accounts[0].Pubkey)
+	assert.Equal(t, AccountData{4, 5}, accounts[0].Account.Data)
+}
+
+func TestClient_GetBlock(t *testing.T) {
+	server, client := NewMockClient(t, map[string]any{})
+	server.SetOpt(BlockOpt, int64(100), map[string]any{
+		"blockTime":         1_700_000_000,
+		"blockHeight":       90,
+		"blockhash":         "hashB",
+		"previousBlockhash": "hashA",
+		"parentSlot":        99,
+	})
+	server.SetOpt(BlockOpt, int64(101), &RPCError{Code: SlotSkippedCode, Message: "Slot 101 was skipped"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	block, err := client.GetBlock(ctx, CommitmentConfirmed, 100, "none")
+	assert.NoError(t, err)
+	assert.Equal(t, "hashB", block.Blockhash)
+	assert.Equal(t, int64(99), block.ParentSlot)
+	assert.Equal(t, int64(1_700_000_000), block.BlockTime)
+
+	_, err = client.GetBlock(ctx, CommitmentConfirmed, 101, "none")
+	assert.True(t, IsSlotSkipped(err))
+
+	_, err = client.GetBlock(ctx, CommitmentConfirmed, 102, "none")
+	assert.True(t, IsBlockNotAvailable(err))
+}
+
+func TestClient_GetBlock_Transactions(t *testing.T) {
+	server, client := NewMockClient(t, map[string]any{})
+	balance := func(index int, amount string) map[string]any {
+		return map[string]any{
+			"accountIndex":  index,
+			"mint":          "mintA",
+			"owner":         "ownerA",
+			"uiTokenAmount": map[string]any{"amount": amount, "decimals": 6, "uiAmountString": "0"},
+		}
+	}
+	server.SetOpt(BlockOpt, int64(100), map[string]any{
+		"blockhash": "hashB",
+		"transactions": []map[string]any{{
+			"transaction": map[string]any{"signatures": []string{"signatureA"}},
+			"meta": map[string]any{
+				"err":               nil,
+				"fee":               5_000,
+				"preTokenBalances":  []any{balance(1, "18446744073709551615")},
+				"postTokenBalances": []any{balance(1, "0"), balance(2, "18446744073709551615")},
+			},
+		}},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	block, err := client.GetBlock(ctx, CommitmentConfirmed, 100, "full")
+	assert.NoError(t, err)
+	if assert.Len(t, block.Transactions, 1) && assert.NotNil(t, block.Transactions[0].Meta) {
+		meta := block.Transactions[0].Meta
+		assert.Nil(t, meta.Err)
+		assert.Equal(t, int64(5_000), meta.Fee)
+		assert.Equal(t, []TokenBalance{{
+			AccountIndex:  1,
+			Mint:          "mintA",
+			Owner:         "ownerA",
+			UiTokenAmount: TokenAmount{Amount: "18446744073709551615", Decimals: 6},
+		}}, meta.PreTokenBalances)
+		assert.Len(t, meta.PostTokenBalances, 2)
+	}
+}
+
 func TestClient_GetVersion_Error(t *testing.T) {
 	server, client := NewMockClient(t, map[string]any{})
 	defer server.Close()
@@ -152,3 +339,92 @@ func TestClient_TestConnection(t *testing.T) {
 	err = badClient.TestConnection(context.Background())
 	assert.Error(t, err)
 }
+
+func TestClient_GetInflationReward(t *testing.T) {
+	server, client := NewMockClient(t, map[string]any{})
+	server.SetOpt(InflationRewardOpt, int64(500), []any{
+		map[string]any{"epoch": 500, "effectiveSlot": 216_000_000, "amount": 2_500, "postBalance": 10_002_500, "commission": 5},
+		nil,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rewards, err := client.GetInflationReward(ctx, CommitmentFinalized, []string{"stakeA", "stakeB"}, 500)
+	assert.NoError(t, err)
+	if assert.Len(t, rewards, 2) {
+		assert.Equal(t, int64(2_500), rewards[0].Amount)
+		assert.Equal(t, int64(10_002_500), rewards[0].PostBalance)
+		assert.Equal(t, 5, *rewards[0].Commission)
+		assert.Nil(t, rewards[1])
+	}
+
+	rewards, err = client.GetInflationReward(ctx, CommitmentFinalized, []string{"stakeA"}, 501)
+	assert.NoError(t, err)
+	assert.Equal(t, []*InflationReward{nil}, rewards)
+}
+
+func TestClient_GetRecentPrioritizationFees(t *testing.T) {
+	_, client := newMethodTester(t, "getRecentPrioritizationFees", []map[string]int64{
+		{"slot": 348_125, "prioritizationFee": 0},
+		{"slot": 348_126, "prioritizationFee": 1_000},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fees, err := client.GetRecentPrioritizationFees(ctx, []string{"accountA"})
+	assert.NoError(t, err)
+	assert.Equal(t, []PrioritizationFee{{Slot: 348_125, PrioritizationFee: 0}, {Slot: 348_126, PrioritizationFee: 1_000}}, fees)
+}
+
+func TestClient_RequestAirdrop(t *testing.T) {
+	server, client := NewMockClient(t, map[string]any{})
+	server.SetOpt(AirdropOpt, "addressA", "confirmed")
+	server.SetOpt(AirdropOpt, "addressB", &RPCError{Code: RateLimitedCode, Message: "Too many requests"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signature, err := client.RequestAirdrop(ctx, CommitmentConfirmed, "addressA", 1_000_000)
+	assert.NoError(t, err)
+	statuses, err := client.GetSignatureStatuses(ctx, []string{signature, "unknown"})
+	assert.NoError(t, err)
+	if assert.Len(t, statuses, 2) && assert.NotNil(t, statuses[0]) {
+		assert.Equal(t, "confirmed", statuses[0].ConfirmationStatus)
+		assert.Nil(t, statuses[0].Err)
+		assert.Nil(t, statuses[1])
+	}
+
+	_, err = client.RequestAirdrop(ctx, CommitmentConfirmed, "addressB", 1_000_000)
+	assert.True(t, IsRateLimited(err))
+
+	// the faucet's generic failure mentions the rate limit whatever went wrong, so only 429 counts
+	_, err = client.RequestAirdrop(ctx, CommitmentConfirmed, "addressC", 1_000_000)
+	assert.Error(t, err)
+	assert.False(t, IsRateLimited(err))
+	assert.False(t, IsRateLimited(&RPCError{Code: -32603, Message: "Internal error"}))
+}
+
+func TestClient_GetNativeHealth(t *testing.T) {
+	server, client := NewMockClient(t, map[string]any{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	health, err := client.GetNativeHealth(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, &HealthStatus{Status: HealthOk}, health)
+	assert.True(t, health.IsHealthy())
+
+	server.SetOpt(NativeHealthOpt, nil, "behind { distance: 42 }\n")
+	health, err = client.GetNativeHealth(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, &HealthStatus{Status: HealthBehind, Message: "behind { distance: 42 }"}, health)
+	assert.False(t, health.IsHealthy())
+
+	// the path of the RPC URL is replaced, as /health is served at the root
+	client.RpcUrl = server.URL() + "/some/path?api-key=secret"
+	_, err = client.GetNativeHealth(ctx)
+	assert.NoError(t, err)
+
+	server.SetOpt(NativeHealthOpt, nil, "<html>Bad Gateway</html>")
+	_, err = client.GetNativeHealth(ctx)
+	assert.Error(t, err)
+}
@@ -7,9 +7,13 @@ import (
 
 const (
 	// RPC Error Codes
-	NodeUnhealthyCode int64 = -32005
-	NodeBehindCode    int64 = -32009
-	TimeoutCode       int64 = -32000
+	NodeUnhealthyCode     int64 = -32005
+	NodeBehindCode        int64 = -32009
+	TimeoutCode           int64 = -32000
+	BlockNotAvailableCode int64 = -32004
+	SlotSkippedCode       int64 = -32007
+	// RateLimitedCode is used by RPC providers, mirroring HTTP 429
+	RateLimitedCode int64 = 429
 )
 
 type (
@@ -46,6 +50,31 @@ func UnpackRpcErrorData[T any](rpcErr *RPCError, formatted T) error {
 	return nil
 }
 
+// DecodeErrorData unpacks the data of known RPC error codes into their typed form.
+// Unknown codes, or errors without data, return the raw data map unchanged.
+func DecodeErrorData(rpcErr *RPCError) (any, error) {
+	if rpcErr.Data == nil {
+		return nil, nil
+	}
+
+	switch rpcErr.Code {
+	case NodeUnhealthyCode:
+		var data NodeUnhealthyErrorData
+		if err := UnpackRpcErrorData(rpcErr, &data); err != nil {
+			return nil, err
+		}
+		return &data, nil
+	case NodeBehindCode:
+		var data NodeBehindErrorData
+		if err := UnpackRpcErrorData(rpcErr, &data); err != nil {
+			return nil, err
+		}
+		return &data, nil
+	default:
+		return rpcErr.Data, nil
+	}
+}
+
 // Helper functions for error checking
 func IsNodeUnhealthy(err error) bool {
 	if rpcErr, ok := err.(*RPCError); ok {
@@ -67,3 +96,29 @@ func IsTimeout(err error) bool {
 	}
 	return false
 }
+
+// IsBlockNotAvailable is true when the block has not reached the node at the requested commitment yet
+func IsBlockNotAvailable(err error) bool {
+	if rpcErr, ok := err.(*RPCError); ok {
+		return rpcErr.Code == BlockNotAvailableCode
+	}
+	return false
+}
+
+// IsSlotSkipped is true when the requested slot was skipped by its leader
+func IsSlotSkipped(err error) bool {
+	if rpcErr, ok := err.(*RPCError); ok {
+		return rpcErr.Code == SlotSkippedCode
+	}
+	return false
+}
+
+// IsRateLimited is true when the node or its provider refused the call with code 429. Faucets
+// report an exhausted limit as a generic internal error that any other failure shares, so it is not
+// recognised as one.
+func IsRateLimited(err error) bool {
+	if rpcErr, ok := err.(*RPCError); ok {
+		return rpcErr.Code == RateLimitedCode
+	}
+	return false
+}
@@ -16,6 +16,19 @@ type MockOpt int
 const (
 	EasyResultsOpt MockOpt = iota
 	BlockTimeOpt
+	// BlockOpt sets the getBlock result of a slot; an *RPCError value is returned as an error
+	BlockOpt
+	// InflationRewardOpt sets the getInflationReward result of an epoch; epochs without one have no rewards.
+	// A map[string]any value sets the reward per address instead, where an *RPCError fails the whole
+	// request like an invalid address does.
+	InflationRewardOpt
+	// AirdropOpt sets the outcome of requestAirdrop to an address: the confirmation status the
+	// airdrop transaction is then reported at by getSignatureStatuses, a map[string]any of status
+	// fields to report instead (e.g. with an "err" for a transaction that landed but failed), or an
+	// *RPCError refusing it. Airdrops to other addresses fail like an exhausted or broken faucet.
+	AirdropOpt
+	// NativeHealthOpt sets the body of GET /health, "ok" by default; the key is ignored
+	NativeHealthOpt
 )
 
 type MockServer struct {
@@ -25,6 +38,13 @@ type MockServer struct {
 
 	easyResults map[string]any
 	blockTimes  map[int64]int64
+	blocks      map[int64]any
+	rewards     map[int64]any
+	airdrops    map[string]any
+	// signatures holds the status fields of each airdrop transaction, by signature
+	signatures map[string]map[string]any
+	// nativeHealth is the body of GET /health
+	nativeHealth string
 }
 
 func NewMockServer(easyResults map[string]any) (*MockServer, error) {
@@ -37,10 +57,17 @@ func NewMockServer(easyResults map[string]any) (*MockServer, error) {
 		listener:    listener,
 		easyResults: easyResults,
 		blockTimes:  make(map[int64]int64),
+		blocks:      make(map[int64]any),
+		rewards:     make(map[int64]any),
+		airdrops:    make(map[string]any),
+		signatures:  make(map[string]map[string]any),
+
+		nativeHealth: HealthOk,
 	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", ms.handleRPCRequest)
+	mux.HandleFunc("/health", ms.handleHealthRequest)
 
 	ms.server = &http.Server{Handler: mux}
 
@@ -73,10 +100,23 @@ func (s *MockServer) SetOpt(opt MockOpt, key any, value any) {
 		s.easyResults[key.(string)] = value
 	case BlockTimeOpt:
 		s.blockTimes[key.(int64)] = value.(int64)
+	case BlockOpt:
+		s.blocks[key.(int64)] = value
+	case InflationRewardOpt:
+		s.rewards[key.(int64)] = value
+	case AirdropOpt:
+		s.airdrops[key.(string)] = value
+	case NativeHealthOpt:
+		s.nativeHealth = value.(string)
 	}
 }
 
 func (s *MockServer) getResult(method string, params ...any) (any, *RPCError) {
+	if method == "requestAirdrop" {
+		// records the airdrop, so it takes the write lock itself
+		return s.requestAirdrop(params...)
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -99,6 +139,77 @@ func (s *MockServer) getResult(method string, params ...any) (any, *RPCError) {
 			Method:  method,
 		}
 
+	case "getBlock":
+		if len(params) == 0 {
+			return nil, &RPCError{
+				Code:    -32602,
+				Message: "Invalid params",
+				Method:  method,
+			}
+		}
+		slot := int64(params[0].(float64))
+		if block, ok := s.blocks[slot]; ok {
+			if rpcErr, ok := block.(*RPCError); ok {
+				rpcErr.Method = method
+				return nil, rpcErr
+			}
+			return block, nil
+		}
+		return nil, &RPCError{
+			Code:    BlockNotAvailableCode,
+			Message: fmt.Sprintf("Block not available for slot %d", slot),
+			Method:  method,
+		}
+
+	case "getSignatureStatuses":
+		if len(params) == 0 {
+			return nil, &RPCError{
+				Code:    -32602,
+				Message: "Invalid params",
+				Method:  method,
+			}
+		}
+		statuses := make([]any, 0)
+		for _, signature := range params[0].([]any) {
+			if fields, ok := s.signatures[signature.(string)]; ok {
+				status := map[string]any{"slot": 100, "confirmations": nil, "err": nil}
+				for key, value := range fields {
+					status[key] = value
+				}
+				statuses = append(statuses, status)
+			} else {
+				statuses = append(statuses, nil)
+			}
+		}
+		return map[string]any{"context": map[string]int{"slot": 100}, "value": statuses}, nil
+
+	case "getInflationReward":
+		if len(params) < 2 {
+			return nil, &RPCError{
+				Code:    -32602,
+				Message: "Invalid params",
+				Method:  method,
+			}
+		}
+		addresses := params[0].([]any)
+		epoch := int64(params[1].(map[string]any)["epoch"].(float64))
+		byAddress, ok := s.rewards[epoch].(map[string]any)
+		if !ok {
+			if rewards, ok := s.rewards[epoch]; ok {
+				return rewards, nil
+			}
+			return make([]any, len(addresses)), nil
+		}
+		rewards := make([]any, len(addresses))
+		for i, address := range addresses {
+			if rpcErr, ok := byAddress[address.(string)].(*RPCError); ok {
+				rpcErr.Method = method
+				return nil, rpcErr
+			}
+			rewards[i] = byAddress[address.(string)]
+		}
+		return rewards, nil
+
 	default:
 		// Fall back to easy results
 		if result, ok := s.easyResults[method]; ok {
@@ -117,6 +228,47 @@ func (s *MockServer) getResult(method string, params ...any) (any, *RPCError) {
 	}
 }
 
+// requestAirdrop simulates a faucet, recording the signature of each airdrop it grants
+func (s *MockServer) requestAirdrop(params ...any) (any, *RPCError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(params) < 2 {
+		return nil, &RPCError{Code: -32602, Message: "Invalid params", Method: "requestAirdrop"}
+	}
+	address := params[0].(string)
+	outcome, ok := s.airdrops[address]
+	if !ok {
+		return nil, &RPCError{
+			Code:    -32603,
+			Message: "Internal error: airdrop request failed. This can happen when the rate limit is reached.",
+			Method:  "requestAirdrop",
+		}
+	}
+	if rpcErr, ok := outcome.(*RPCError); ok {
+		rpcErr.Method = "requestAirdrop"
+		return nil, rpcErr
+	}
+
+	signature := fmt.Sprintf("airdrop%d", len(s.signatures)+1)
+	if fields, ok := outcome.(map[string]any); ok {
+		s.signatures[signature] = fields
+	} else {
+		s.signatures[signature] = map[string]any{"confirmationStatus": outcome.(string)}
+	}
+	return signature, nil
+}
+
+func (s *MockServer) handleHealthRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, _ = w.Write([]byte(s.nativeHealth))
+}
+
 func (s *MockServer) handleRPCRequest(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
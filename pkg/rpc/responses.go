@@ -1,5 +1,11 @@
 package rpc
 
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
 type (
 	Response[T any] struct {
 		Jsonrpc string   `json:"jsonrpc"`
@@ -38,6 +44,7 @@ type (
 		Status string `json:"status"` // "confirmed", "processed", "finalized"
 	}
 
+	// HealthStatus is the answer of the native GET /health endpoint
 	HealthStatus struct {
 		Status      string `json:"status"`
 		Message     string `json:"message,omitempty"`
@@ -50,10 +57,121 @@ type (
 	}
 
 	Block struct {
-		BlockTime       int64         `json:"blockTime"`
-		NumTransactions int           `json:"numTransactions"`
-		Fee             int           `json:"fee"`
-		Rewards         []BlockReward `json:"rewards,omitempty"`
+		BlockTime         int64         `json:"blockTime"`
+		BlockHeight       int64         `json:"blockHeight"`
+		Blockhash         string        `json:"blockhash"`
+		PreviousBlockhash string        `json:"previousBlockhash"`
+		ParentSlot        int64         `json:"parentSlot"`
+		NumTransactions   int           `json:"numTransactions"`
+		Fee               int           `json:"fee"`
+		Rewards           []BlockReward `json:"rewards,omitempty"`
+		// Transactions is only set when the block is fetched with full transaction details
+		Transactions []BlockTransaction `json:"transactions,omitempty"`
+	}
+
+	// BlockTransaction is a transaction of a block; only its status metadata is decoded
+	BlockTransaction struct {
+		Meta *TransactionMeta `json:"meta"`
+	}
+
+	TransactionMeta struct {
+		Err               any            `json:"err"`
+		Fee               int64          `json:"fee"`
+		PreTokenBalances  []TokenBalance `json:"preTokenBalances"`
+		PostTokenBalances []TokenBalance `json:"postTokenBalances"`
+	}
+
+	// TokenBalance is the balance of a token account involved in a transaction, identified by the
+	// index of the account in the transaction
+	TokenBalance struct {
+		AccountIndex  int         `json:"accountIndex"`
+		Mint          string      `json:"mint"`
+		Owner         string      `json:"owner"`
+		UiTokenAmount TokenAmount `json:"uiTokenAmount"`
+	}
+
+	TokenAmount struct {
+		// Amount is the raw amount, a string as it may not fit a float64
+		Amount   string `json:"amount"`
+		Decimals int    `json:"decimals"`
+	}
+
+	ClusterNode struct {
+		Pubkey       string  `json:"pubkey"`
+		Gossip       *string `json:"gossip"`
+		TPU          *string `json:"tpu"`
+		RPC          *string `json:"rpc"`
+		Version      *string `json:"version"`
+		FeatureSet   *uint32 `json:"featureSet"`
+		ShredVersion *uint16 `json:"shredVersion"`
+	}
+
+	VoteAccount struct {
+		VotePubkey       string     `json:"votePubkey"`
+		NodePubkey       string     `json:"nodePubkey"`
+		ActivatedStake   int64      `json:"activatedStake"`
+		EpochVoteAccount bool       `json:"epochVoteAccount"`
+		Commission       int        `json:"commission"`
+		LastVote         int64      `json:"lastVote"`
+		RootSlot         int64      `json:"rootSlot"`
+		EpochCredits     [][3]int64 `json:"epochCredits"`
+	}
+
+	VoteAccounts struct {
+		Current    []VoteAccount `json:"current"`
+		Delinquent []VoteAccount `json:"delinquent"`
+	}
+
+	// AccountData is account data requested with base64 encoding, decoded from its ["<data>", "base64"] form
+	AccountData []byte
+
+	AccountInfo struct {
+		Data       AccountData `json:"data"`
+		Executable bool        `json:"executable"`
+		Lamports   int64       `json:"lamports"`
+		Owner      string      `json:"owner"`
+		Space      int64       `json:"space"`
+	}
+
+	ProgramAccount struct {
+		Pubkey  string      `json:"pubkey"`
+		Account AccountInfo `json:"account"`
+	}
+
+	// MemcmpFilter matches accounts whose data at Offset equals Bytes (base58 unless Encoding says otherwise)
+	MemcmpFilter struct {
+		Offset   int    `json:"offset"`
+		Bytes    string `json:"bytes"`
+		Encoding string `json:"encoding,omitempty"`
+	}
+
+	ProgramAccountFilter struct {
+		Memcmp   *MemcmpFilter `json:"memcmp,omitempty"`
+		DataSize *int          `json:"dataSize,omitempty"`
+	}
+
+	// InflationReward is the staking reward credited to an account at the start of the epoch after Epoch
+	InflationReward struct {
+		Epoch         int64 `json:"epoch"`
+		EffectiveSlot int64 `json:"effectiveSlot"`
+		Amount        int64 `json:"amount"`
+		PostBalance   int64 `json:"postBalance"`
+		Commission    *int  `json:"commission"`
+	}
+
+	// PrioritizationFee is the lowest priority fee, in micro-lamports per compute unit, that landed a
+	// transaction locking the requested accounts in Slot
+	PrioritizationFee struct {
+		Slot              int64 `json:"slot"`
+		PrioritizationFee int64 `json:"prioritizationFee"`
+	}
+
+	SignatureStatus struct {
+		Slot int64 `json:"slot"`
+		// Confirmations is nil once the transaction is finalized
+		Confirmations      *int64 `json:"confirmations"`
+		Err                any    `json:"err"`
+		ConfirmationStatus string `json:"confirmationStatus"`
 	}
 
 	BlockReward struct {
@@ -63,6 +181,22 @@ type (
 	}
 )
 
+func (d *AccountData) UnmarshalJSON(data []byte) error {
+	var encoded []string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return fmt.Errorf("failed to decode account data: %w", err)
+	}
+	if len(encoded) != 2 || encoded[1] != "base64" {
+		return fmt.Errorf("unexpected account data encoding: %v", encoded)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded[0])
+	if err != nil {
+		return fmt.Errorf("failed to decode account data: %w", err)
+	}
+	*d = decoded
+	return nil
+}
+
 // Helper methods for HealthStatus
 func (h *HealthStatus) IsHealthy() bool {
 	return h.Status == "ok"
@@ -0,0 +1,236 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	PriorityCritical Priority = iota
+	PriorityNormal
+	PriorityBulk
+	numPriorities
+)
+
+// ErrQueueDeadline is returned when a call waited longer than its class' queue deadline
+var ErrQueueDeadline = errors.New("queue deadline exceeded")
+
+type (
+	// Priority is the scheduling class of an RPC call
+	Priority int
+
+	priorityKey struct{}
+
+	// ClassLimits bounds a priority class. Zero values mean no limit.
+	ClassLimits struct {
+		MaxConcurrent int
+		QueueDeadline time.Duration
+	}
+
+	// SchedulerObserver receives the queue wait of every call passing through a Scheduler
+	SchedulerObserver interface {
+		ObserveWait(priority Priority, wait time.Duration, admitted bool)
+	}
+
+	// Scheduler orders RPC calls by priority in front of a Client. Normal and bulk
+	// calls share maxConcurrent in-flight slots and are dispatched strictly by
+	// priority; critical calls are only bounded by their own class limit, so bulk
+	// work can never hold them up.
+	Scheduler struct {
+		mu            sync.Mutex
+		maxConcurrent int
+		inFlight      int
+		classes       [numPriorities]*schedulerClass
+
+		Observer SchedulerObserver
+	}
+
+	schedulerClass struct {
+		limits   ClassLimits
+		inFlight int
+		queue    []*schedulerWaiter
+	}
+
+	schedulerWaiter struct {
+		ready   chan struct{}
+		granted bool
+	}
+)
+
+// DefaultClassLimits keeps health and slot checks responsive while bounding heavy calls
+var DefaultClassLimits = map[Priority]ClassLimits{
+	PriorityCritical: {MaxConcurrent: 4, QueueDeadline: 5 * time.Second},
+	PriorityNormal:   {QueueDeadline: 30 * time.Second},
+	PriorityBulk:     {MaxConcurrent: 2, QueueDeadline: 60 * time.Second},
+}
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityCritical:
+		return "critical"
+	case PriorityNormal:
+		return "normal"
+	case PriorityBulk:
+		return "bulk"
+	default:
+		return fmt.Sprintf("priority(%d)", int(p))
+	}
+}
+
+// Priorities lists all scheduling classes, highest first
+func Priorities() []Priority {
+	return []Priority{PriorityCritical, PriorityNormal, PriorityBulk}
+}
+
+// WithPriority tags ctx so that calls made with it are scheduled in the given class
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFromContext returns the class set by WithPriority, or PriorityNormal
+func PriorityFromContext(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok && priority >= 0 && priority < numPriorities {
+		return priority
+	}
+	return PriorityNormal
+}
+
+func NewScheduler(maxConcurrent int, limits map[Priority]ClassLimits) *Scheduler {
+	scheduler := &Scheduler{maxConcurrent: maxConcurrent}
+	for _, priority := range Priorities() {
+		scheduler.classes[priority] = &schedulerClass{limits: limits[priority]}
+	}
+	return scheduler
+}
+
+// Acquire blocks until a call of the given priority may run, and returns the function
+// that must be called once it completes.
+func (s *Scheduler) Acquire(ctx context.Context, priority Priority) (func(), error) {
+	start := time.Now()
+	class := s.classes[priority]
+
+	s.mu.Lock()
+	if len(class.queue) == 0 && s.canRun(priority) {
+		s.start(priority)
+		s.mu.Unlock()
+		s.observe(priority, 0, true)
+		return s.releaseFunc(priority), nil
+	}
+	waiter := &schedulerWaiter{ready: make(chan struct{})}
+	class.queue = append(class.queue, waiter)
+	s.mu.Unlock()
+
+	var deadline <-chan time.Time
+	if class.limits.QueueDeadline > 0 {
+		timer := time.NewTimer(class.limits.QueueDeadline)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	var err error
+	select {
+	case <-waiter.ready:
+	case <-deadline:
+		err = ErrQueueDeadline
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		s.mu.Lock()
+		// the slot may have been granted while we were giving up; keep it in that case
+		if !waiter.granted {
+			s.remove(priority, waiter)
+			s.mu.Unlock()
+			s.observe(priority, time.Since(start), false)
+			return nil, fmt.Errorf("%s call not scheduled: %w", priority, err)
+		}
+		s.mu.Unlock()
+	}
+
+	s.observe(priority, time.Since(start), true)
+	return s.releaseFunc(priority), nil
+}
+
+// QueueDepth returns the number of calls of the given priority waiting for a slot
+func (s *Scheduler) QueueDepth(priority Priority) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.classes[priority].queue)
+}
+
+// InFlight returns the number of running calls of the given priority
+func (s *Scheduler) InFlight(priority Priority) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.classes[priority].inFlight
+}
+
+// canRun must be called with s.mu held
+func (s *Scheduler) canRun(priority Priority) bool {
+	class := s.classes[priority]
+	if class.limits.MaxConcurrent > 0 && class.inFlight >= class.limits.MaxConcurrent {
+		return false
+	}
+	if priority == PriorityCritical {
+		return true
+	}
+	return s.maxConcurrent <= 0 || s.inFlight < s.maxConcurrent
+}
+
+// start must be called with s.mu held
+func (s *Scheduler) start(priority Priority) {
+	s.classes[priority].inFlight++
+	if priority != PriorityCritical {
+		s.inFlight++
+	}
+}
+
+// remove must be called with s.mu held
+func (s *Scheduler) remove(priority Priority, waiter *schedulerWaiter) {
+	class := s.classes[priority]
+	for i, queued := range class.queue {
+		if queued == waiter {
+			class.queue = append(class.queue[:i], class.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *Scheduler) releaseFunc(priority Priority) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.classes[priority].inFlight--
+			if priority != PriorityCritical {
+				s.inFlight--
+			}
+			s.dispatch()
+		})
+	}
+}
+
+// dispatch hands free slots to queued calls, highest priority first. Must be called with s.mu held.
+func (s *Scheduler) dispatch() {
+	for _, priority := range Priorities() {
+		class := s.classes[priority]
+		for len(class.queue) > 0 && s.canRun(priority) {
+			waiter := class.queue[0]
+			class.queue = class.queue[1:]
+			waiter.granted = true
+			s.start(priority)
+			close(waiter.ready)
+		}
+	}
+}
+
+func (s *Scheduler) observe(priority Priority, wait time.Duration, admitted bool) {
+	if s.Observer != nil {
+		s.Observer.ObserveWait(priority, wait, admitted)
+	}
+}
@@ -0,0 +1,88 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriorityFromContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, PriorityNormal, PriorityFromContext(ctx))
+	assert.Equal(t, PriorityBulk, PriorityFromContext(WithPriority(ctx, PriorityBulk)))
+	assert.Equal(t, PriorityNormal, PriorityFromContext(WithPriority(ctx, Priority(42))))
+	assert.Equal(t, "critical", PriorityCritical.String())
+}
+
+func TestScheduler_PriorityOrder(t *testing.T) {
+	scheduler := NewScheduler(1, map[Priority]ClassLimits{})
+	ctx := context.Background()
+
+	release, err := scheduler.Acquire(ctx, PriorityNormal)
+	assert.NoError(t, err)
+
+	order := make(chan Priority, 2)
+	acquire := func(priority Priority) {
+		done, err := scheduler.Acquire(ctx, priority)
+		assert.NoError(t, err)
+		order <- priority
+		done()
+	}
+	go acquire(PriorityBulk)
+	assert.Eventually(t, func() bool { return scheduler.QueueDepth(PriorityBulk) == 1 }, time.Second, time.Millisecond)
+	go acquire(PriorityNormal)
+	assert.Eventually(t, func() bool { return scheduler.QueueDepth(PriorityNormal) == 1 }, time.Second, time.Millisecond)
+
+	// critical calls do not wait behind the shared limit
+	criticalRelease, err := scheduler.Acquire(ctx, PriorityCritical)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, scheduler.InFlight(PriorityCritical))
+	criticalRelease()
+
+	release()
+	assert.Equal(t, PriorityNormal, <-order)
+	assert.Equal(t, PriorityBulk, <-order)
+	assert.Equal(t, 0, scheduler.InFlight(PriorityNormal))
+	assert.Equal(t, 0, scheduler.InFlight(PriorityBulk))
+}
+
+func TestScheduler_QueueDeadline(t *testing.T) {
+	scheduler := NewScheduler(0, map[Priority]ClassLimits{
+		PriorityBulk: {MaxConcurrent: 1, QueueDeadline: 20 * time.Millisecond},
+	})
+	ctx := context.Background()
+
+	release, err := scheduler.Acquire(ctx, PriorityBulk)
+	assert.NoError(t, err)
+	defer release()
+
+	_, err = scheduler.Acquire(ctx, PriorityBulk)
+	assert.True(t, errors.Is(err, ErrQueueDeadline))
+	assert.Equal(t, 0, scheduler.QueueDepth(PriorityBulk))
+
+	// other classes are not affected by the bulk class limit
+	normalRelease, err := scheduler.Acquire(ctx, PriorityNormal)
+	assert.NoError(t, err)
+	normalRelease()
+}
+
+func TestClient_Scheduler(t *testing.T) {
+	_, client := newMethodTester(t, "minimumLedgerSlot", int64(250))
+	client.Scheduler = NewScheduler(1, map[Priority]ClassLimits{
+		PriorityBulk: {QueueDeadline: 20 * time.Millisecond},
+	})
+
+	release, err := client.Scheduler.Acquire(context.Background(), PriorityNormal)
+	assert.NoError(t, err)
+
+	_, err = client.GetMinimumLedgerSlot(WithPriority(context.Background(), PriorityBulk))
+	assert.True(t, errors.Is(err, ErrQueueDeadline))
+
+	release()
+	slot, err := client.GetMinimumLedgerSlot(WithPriority(context.Background(), PriorityBulk))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(250), slot)
+}
@@ -0,0 +1,132 @@
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	PhaseDNS       = "dns"
+	PhaseConnect   = "connect"
+	PhaseTLS       = "tls"
+	PhaseFirstByte = "first_byte"
+	PhaseBodyRead  = "body_read"
+
+	// OutcomeSuccess is a request that returned a result
+	OutcomeSuccess = "success"
+	// OutcomeRPCError is a request the node answered with a JSON-RPC error
+	OutcomeRPCError = "rpc_error"
+	// OutcomeTimeout is a request that timed out before a response was read
+	OutcomeTimeout = "timeout"
+	// OutcomeError is a request that failed otherwise, e.g. refused connections or undecodable responses
+	OutcomeError = "error"
+)
+
+type (
+	// TraceObserver receives per-phase timings of every RPC request made by a Client
+	TraceObserver interface {
+		// ObservePhase receives the duration of a phase, labelled with the outcome of its request
+		ObservePhase(endpoint string, phase string, outcome string, duration time.Duration)
+		ObserveConnection(endpoint string, reused bool)
+	}
+
+	// requestTrace records httptrace timestamps for a single request. Dial hooks may
+	// fire from several goroutines at once, hence the mutex.
+	requestTrace struct {
+		mu sync.Mutex
+
+		dnsStart, dnsDone         time.Time
+		connectStart, connectDone time.Time
+		tlsStart, tlsDone         time.Time
+		wroteRequest, firstByte   time.Time
+		gotConn, reused           bool
+	}
+)
+
+// EndpointLabel reduces an RPC URL to its host, so that API keys carried in the
+// path or query string never end up in metric labels.
+func EndpointLabel(rpcUrl string) string {
+	parsed, err := url.Parse(rpcUrl)
+	if err != nil || parsed.Host == "" {
+		return "unknown"
+	}
+	return parsed.Host
+}
+
+func (t *requestTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.mark(&t.dnsDone) },
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			// keep the first attempt when dialing several addresses in parallel
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_ string, _ string, err error) {
+			if err == nil {
+				t.mark(&t.connectDone)
+			}
+		},
+		TLSHandshakeStart: func() { t.mark(&t.tlsStart) },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				t.mark(&t.tlsDone)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.gotConn = true
+			t.reused = info.Reused
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.mark(&t.wroteRequest) },
+		GotFirstResponseByte: func() { t.mark(&t.firstByte) },
+	}
+}
+
+func (t *requestTrace) mark(field *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*field = time.Now()
+}
+
+// transportOutcome classifies a request that failed before a response was read
+func transportOutcome(err error) string {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return OutcomeTimeout
+	}
+	return OutcomeError
+}
+
+// report forwards the completed phases to the observer; phases that did not happen
+// (e.g. dns and connect on a reused connection, or body_read of a failed request) are
+// skipped rather than reported as 0.
+func (t *requestTrace) report(observer TraceObserver, endpoint, outcome string, bodyDone time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	observe := func(phase string, start, end time.Time) {
+		if !start.IsZero() && !end.IsZero() && !end.Before(start) {
+			observer.ObservePhase(endpoint, phase, outcome, end.Sub(start))
+		}
+	}
+	observe(PhaseDNS, t.dnsStart, t.dnsDone)
+	observe(PhaseConnect, t.connectStart, t.connectDone)
+	observe(PhaseTLS, t.tlsStart, t.tlsDone)
+	observe(PhaseFirstByte, t.wroteRequest, t.firstByte)
+	observe(PhaseBodyRead, t.firstByte, bodyDone)
+
+	if t.gotConn {
+		observer.ObserveConnection(endpoint, t.reused)
+	}
+}
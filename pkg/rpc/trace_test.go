@@ -0,0 +1,97 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingObserver struct {
+	mu          sync.Mutex
+	phases      map[string]int
+	outcomes    map[string]int
+	connections []bool
+}
+
+func newRecordingObserver() *recordingObserver {
+	return &recordingObserver{phases: make(map[string]int), outcomes: make(map[string]int)}
+}
+
+func (o *recordingObserver) ObservePhase(endpoint string, phase string, outcome string, duration time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.phases[phase]++
+	o.outcomes[outcome]++
+}
+
+func (o *recordingObserver) ObserveConnection(endpoint string, reused bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.connections = append(o.connections, reused)
+}
+
+func TestEndpointLabel(t *testing.T) {
+	assert.Equal(t, "localhost:8899", EndpointLabel("http://localhost:8899"))
+	assert.Equal(t, "mainnet.example.com", EndpointLabel("https://mainnet.example.com/?api-key=secret"))
+	assert.Equal(t, "rpc.example.com", EndpointLabel("https://rpc.example.com/secret-token/"))
+	assert.Equal(t, "unknown", EndpointLabel("not a url"))
+}
+
+func TestClient_TraceObserver(t *testing.T) {
+	_, client := newMethodTester(t, "minimumLedgerSlot", int64(250))
+	observer := newRecordingObserver()
+	client.Observer = observer
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		_, err := client.GetMinimumLedgerSlot(ctx)
+		assert.NoError(t, err)
+	}
+
+	// the mock server listens on an IP literal over plain http: no dns or tls phases,
+	// and only the first request has to connect.
+	assert.Equal(t, 0, observer.phases[PhaseDNS])
+	assert.Equal(t, 0, observer.phases[PhaseTLS])
+	assert.Equal(t, 1, observer.phases[PhaseConnect])
+	assert.Equal(t, 2, observer.phases[PhaseFirstByte])
+	assert.Equal(t, 2, observer.phases[PhaseBodyRead])
+	assert.Equal(t, []bool{false, true}, observer.connections)
+	assert.Equal(t, map[string]int{OutcomeSuccess: 5}, observer.outcomes)
+}
+
+func TestClient_TraceObserverFailures(t *testing.T) {
+	// a node that never answers: the connect phase is still reported, with the request's outcome
+	release := make(chan struct{})
+	stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer stalled.Close()
+	defer close(release)
+	client := NewRPCClient(stalled.URL, 50*time.Millisecond)
+	observer := newRecordingObserver()
+	client.Observer = observer
+
+	_, err := client.GetMinimumLedgerSlot(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 1, observer.phases[PhaseConnect])
+	assert.Equal(t, 0, observer.phases[PhaseBodyRead])
+	assert.Equal(t, map[string]int{OutcomeTimeout: 1}, observer.outcomes)
+
+	server, client := NewMockClient(t, map[string]any{})
+	server.SetOpt(EasyResultsOpt, "minimumLedgerSlot", &RPCError{Code: -32001, Message: "boom"})
+	observer = newRecordingObserver()
+	client.Observer = observer
+
+	_, err = client.GetMinimumLedgerSlot(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 1, observer.phases[PhaseBodyRead])
+	assert.Equal(t, 3, observer.outcomes[OutcomeRPCError])
+}